@@ -0,0 +1,415 @@
+package gout
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrDigestAlgorithm = errors.New("gout:unsupported digest algorithm")
+	ErrDigestQop       = errors.New("gout:unsupported digest qop")
+	ErrDigestBody      = errors.New("gout:digest auth-int needs a replayable body")
+)
+
+type authType int
+
+const (
+	authBasic authType = iota + 1
+	authBearer
+	authDigest
+)
+
+type auth struct {
+	typ      authType
+	user     string
+	password string
+	token    string
+}
+
+// SetBasicAuth 设置http basic认证
+func (df *DataFlow) SetBasicAuth(user, password string) *DataFlow {
+	df.Req.auth = &auth{typ: authBasic, user: user, password: password}
+	return df
+}
+
+// SetBearerToken 设置Authorization: Bearer <token>
+func (df *DataFlow) SetBearerToken(token string) *DataFlow {
+	df.Req.auth = &auth{typ: authBearer, token: token}
+	return df
+}
+
+// SetDigestAuth 设置http digest认证(RFC 7616)
+// 第一次请求收到401之后计算摘要再重发一次, 后面的请求复用服务端给的nonce
+func (df *DataFlow) SetDigestAuth(user, password string) *DataFlow {
+	df.Req.auth = &auth{typ: authDigest, user: user, password: password}
+	return df
+}
+
+// SetDefaultBasicAuth 设置默认的basic认证, 请求里的认证设置会覆盖它
+func (g *Gout) SetDefaultBasicAuth(user, password string) *Gout {
+	g.defAuth = &auth{typ: authBasic, user: user, password: password}
+	return g
+}
+
+// SetDefaultBearerToken 设置默认的bearer token, 请求里的认证设置会覆盖它
+func (g *Gout) SetDefaultBearerToken(token string) *Gout {
+	g.defAuth = &auth{typ: authBearer, token: token}
+	return g
+}
+
+// SetDefaultDigestAuth 设置默认的digest认证, 请求里的认证设置会覆盖它
+func (g *Gout) SetDefaultDigestAuth(user, password string) *Gout {
+	g.defAuth = &auth{typ: authDigest, user: user, password: password}
+	return g
+}
+
+// 请求里的认证优先, 默认认证不覆盖SetHeader设置的Authorization
+func (r *Req) getAuth(req *http.Request) *auth {
+	if r.auth != nil {
+		return r.auth
+	}
+
+	if r.g != nil && r.g.defAuth != nil && req.Header.Get("Authorization") == "" {
+		return r.g.defAuth
+	}
+
+	return nil
+}
+
+// setAuth 在request()里确定这次请求的认证方式, 重试的时候Authorization已经设置过了, 不能再根据header判断
+func (r *Req) setAuth(req *http.Request) {
+	a := r.getAuth(req)
+	r.sendAuth = a
+	// 请求里没有设置认证才使用oauth2
+	r.sendOAuth2 = a == nil && r.g != nil && r.g.oauth2 != nil && req.Header.Get("Authorization") == ""
+	if a == nil {
+		return
+	}
+
+	switch a.typ {
+	case authBasic:
+		req.SetBasicAuth(a.user, a.password)
+	case authBearer:
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+}
+
+// send 发送请求, 需要digest认证或者oauth2的时候处理401
+func (r *Req) send(req *http.Request) (*http.Response, error) {
+	a := r.sendAuth
+	if a != nil && a.typ == authDigest {
+		return r.g.digest.do(r.g, req, a)
+	}
+
+	if r.sendOAuth2 {
+		return r.g.oauth2.do(r.g, req)
+	}
+
+	return r.g.do(req)
+}
+
+// digestCache 按scheme://host保存服务端最近一次的质询, 用于计算nc
+type digestCache struct {
+	mu sync.Mutex
+	m  map[string]*digestChallenge
+}
+
+func (d *digestCache) get(key string) *digestChallenge {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.m[key]
+}
+
+func (d *digestCache) set(key string, c *digestChallenge) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.m == nil {
+		d.m = make(map[string]*digestChallenge)
+	}
+	d.m[key] = c
+}
+
+func (d *digestCache) do(g *Gout, req *http.Request, a *auth) (*http.Response, error) {
+	key := req.URL.Scheme + "://" + req.URL.Host
+
+	// 有缓存的质询, 直接带上认证信息
+	if c := d.get(key); c != nil {
+		if err := c.authorize(req, a); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := g.do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	c, ok := parseDigestChallenge(resp.Header)
+	if !ok {
+		return resp, nil
+	}
+
+	// 不能重放body, 把401交给调用者
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if req.GetBody != nil {
+		if req.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	d.set(key, c)
+	if err := c.authorize(req, a); err != nil {
+		return nil, err
+	}
+
+	return g.do(req)
+}
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string // 空表示RFC 2069的兼容模式
+	userhash  bool
+
+	mu sync.Mutex
+	nc uint32
+}
+
+// 从WWW-Authenticate里找出Digest质询
+func parseDigestChallenge(h http.Header) (*digestChallenge, bool) {
+	for _, v := range h["Www-Authenticate"] {
+		if len(v) < 7 || !strings.EqualFold(v[:7], "Digest ") {
+			continue
+		}
+
+		params := parseAuthParams(v[7:])
+		c := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+			userhash:  strings.EqualFold(params["userhash"], "true"),
+		}
+
+		if c.algorithm == "" {
+			c.algorithm = "MD5"
+		}
+
+		// 两个都支持的时候选择auth
+		var auth, authInt bool
+		for _, q := range strings.Split(params["qop"], ",") {
+			switch strings.TrimSpace(q) {
+			case "auth":
+				auth = true
+			case "auth-int":
+				authInt = true
+			}
+		}
+
+		switch {
+		case auth:
+			c.qop = "auth"
+		case authInt:
+			c.qop = "auth-int"
+		case params["qop"] != "":
+			c.qop = params["qop"]
+		}
+
+		return c, true
+	}
+
+	return nil, false
+}
+
+// key=value, key="quoted value" 逗号分隔
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq == -1 {
+			return params
+		}
+
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			val = b.String()
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end == -1 {
+				end = len(s)
+			}
+			val = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+
+		params[key] = val
+	}
+}
+
+func digestHash(algorithm string) (func() hash.Hash, bool, error) {
+	sess := false
+	alg := strings.ToUpper(algorithm)
+	if strings.HasSuffix(alg, "-SESS") {
+		sess = true
+		alg = strings.TrimSuffix(alg, "-SESS")
+	}
+
+	switch alg {
+	case "MD5":
+		return md5.New, sess, nil
+	case "SHA-256":
+		return sha256.New, sess, nil
+	case "SHA-512-256":
+		return sha512.New512_256, sess, nil
+	}
+
+	return nil, false, ErrDigestAlgorithm
+}
+
+func hashHex(newHash func() hash.Hash, s string) string {
+	h := newHash()
+	io.WriteString(h, s)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func newCnonce() string {
+	b := make([]byte, 16)
+	io.ReadFull(rand.Reader, b)
+	return hex.EncodeToString(b)
+}
+
+func (c *digestChallenge) nextNC() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nc++
+	return c.nc
+}
+
+// authorize 计算摘要, 设置Authorization
+func (c *digestChallenge) authorize(req *http.Request, a *auth) error {
+	return c.authorizeWith(req, a, newCnonce())
+}
+
+func (c *digestChallenge) authorizeWith(req *http.Request, a *auth, cnonce string) error {
+	newHash, sess, err := digestHash(c.algorithm)
+	if err != nil {
+		return err
+	}
+
+	uri := req.URL.RequestURI()
+
+	ha1 := hashHex(newHash, a.user+":"+c.realm+":"+a.password)
+	if sess {
+		ha1 = hashHex(newHash, ha1+":"+c.nonce+":"+cnonce)
+	}
+
+	var ha2 string
+	switch c.qop {
+	case "", "auth":
+		ha2 = hashHex(newHash, req.Method+":"+uri)
+	case "auth-int":
+		body, err := replayBody(req)
+		if err != nil {
+			return err
+		}
+		ha2 = hashHex(newHash, req.Method+":"+uri+":"+hashHex(newHash, string(body)))
+	default:
+		return ErrDigestQop
+	}
+
+	username := a.user
+	if c.userhash {
+		username = hashHex(newHash, a.user+":"+c.realm)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s`,
+		quoteEscape(username), quoteEscape(c.realm), quoteEscape(c.nonce), quoteEscape(uri), c.algorithm)
+
+	if c.qop == "" {
+		fmt.Fprintf(&b, `, response="%s"`, hashHex(newHash, ha1+":"+c.nonce+":"+ha2))
+	} else {
+		nc := fmt.Sprintf("%08x", c.nextNC())
+		response := hashHex(newHash, ha1+":"+c.nonce+":"+nc+":"+cnonce+":"+c.qop+":"+ha2)
+		fmt.Fprintf(&b, `, response="%s", qop=%s, nc=%s, cnonce="%s"`, response, c.qop, nc, cnonce)
+	}
+
+	if c.opaque != "" {
+		fmt.Fprintf(&b, `, opaque="%s"`, quoteEscape(c.opaque))
+	}
+
+	if c.userhash {
+		b.WriteString(", userhash=true")
+	}
+
+	req.Header.Set("Authorization", b.String())
+	return nil
+}
+
+func quoteEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// auth-int需要body的摘要, 通过GetBody读一份, 不影响要发送的Body
+func replayBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody == nil {
+		return nil, ErrDigestBody
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return all, resetBody(req)
+}
@@ -0,0 +1,272 @@
+package gout
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testDigestRealm = "gout@example.org"
+	testDigestNonce = "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+)
+
+func md5Hex(s string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(s)))
+}
+
+// 服务端校验digest认证, 只支持MD5
+func checkDigest(c *gin.Context, qop, password string, body []byte) bool {
+	h := c.GetHeader("Authorization")
+	if !strings.HasPrefix(h, "Digest ") {
+		return false
+	}
+
+	p := parseAuthParams(h[7:])
+	if p["nonce"] != testDigestNonce || p["realm"] != testDigestRealm || p["qop"] != qop {
+		return false
+	}
+
+	ha1 := md5Hex(p["username"] + ":" + testDigestRealm + ":" + password)
+	ha2 := md5Hex(c.Request.Method + ":" + p["uri"])
+	if qop == "auth-int" {
+		ha2 = md5Hex(c.Request.Method + ":" + p["uri"] + ":" + md5Hex(string(body)))
+	}
+
+	return p["response"] == md5Hex(ha1+":"+p["nonce"]+":"+p["nc"]+":"+p["cnonce"]+":"+qop+":"+ha2)
+}
+
+func setupAuth(t *testing.T, total *int32, ncs *[]string) *gin.Engine {
+	router := gin.New()
+
+	router.GET("/basic", func(c *gin.Context) {
+		user, password, ok := c.Request.BasicAuth()
+		if !ok {
+			c.String(401, "")
+			return
+		}
+		c.String(200, user+":"+password)
+	})
+
+	router.GET("/bearer", func(c *gin.Context) {
+		c.String(200, c.GetHeader("Authorization"))
+	})
+
+	digest := func(qop string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			atomic.AddInt32(total, 1)
+			body, _ := ioutil.ReadAll(c.Request.Body)
+			if !checkDigest(c, qop, "password", body) {
+				c.Header("WWW-Authenticate", `Basic realm="other"`)
+				c.Writer.Header().Add("WWW-Authenticate",
+					fmt.Sprintf(`Digest realm="%s", qop="%s", nonce="%s", opaque="5ccc069c403ebaf9f0171e9517f40e41"`,
+						testDigestRealm, qop, testDigestNonce))
+				c.String(401, "")
+				return
+			}
+
+			*ncs = append(*ncs, parseAuthParams(c.GetHeader("Authorization")[7:])["nc"])
+			c.String(200, "ok:"+string(body))
+		}
+	}
+
+	router.GET("/digest", digest("auth"))
+	router.POST("/digest-int", digest("auth-int"))
+	return router
+}
+
+func Test_Auth_BasicBearer(t *testing.T) {
+	var total int32
+	var ncs []string
+	router := setupAuth(t, &total, &ncs)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	s := ""
+	assert.NoError(t, GET(ts.URL+"/basic").SetBasicAuth("user", "pass").BindBody(&s).Do())
+	assert.Equal(t, "user:pass", s)
+
+	assert.NoError(t, GET(ts.URL+"/bearer").SetBearerToken("token").BindBody(&s).Do())
+	assert.Equal(t, "Bearer token", s)
+
+	// 默认认证, 请求里的设置优先
+	g := New().SetDefaultBasicAuth("def", "def-pass")
+	assert.NoError(t, g.GET(ts.URL+"/basic").BindBody(&s).Do())
+	assert.Equal(t, "def:def-pass", s)
+
+	assert.NoError(t, g.GET(ts.URL+"/basic").SetBasicAuth("user", "pass").BindBody(&s).Do())
+	assert.Equal(t, "user:pass", s)
+
+	g = New().SetDefaultBearerToken("def-token")
+	assert.NoError(t, g.GET(ts.URL+"/bearer").BindBody(&s).Do())
+	assert.Equal(t, "Bearer def-token", s)
+
+	assert.NoError(t, g.GET(ts.URL+"/bearer").SetHeader(H{"Authorization": "Custom x"}).BindBody(&s).Do())
+	assert.Equal(t, "Custom x", s)
+
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("a:b"))
+	assert.NoError(t, g.GET(ts.URL+"/bearer").SetBasicAuth("a", "b").BindBody(&s).Do())
+	assert.Equal(t, want, s)
+}
+
+func Test_Auth_Digest(t *testing.T) {
+	var total int32
+	var ncs []string
+	router := setupAuth(t, &total, &ncs)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	g := New()
+	s := ""
+	code := 0
+	assert.NoError(t, g.GET(ts.URL+"/digest").SetDigestAuth("user", "password").BindBody(&s).Code(&code).Do())
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "ok:", s)
+	assert.Equal(t, int32(2), total)
+
+	// 复用nonce, 不会再收到401
+	assert.NoError(t, g.GET(ts.URL+"/digest").SetDigestAuth("user", "password").Code(&code).Do())
+	assert.Equal(t, 200, code)
+	assert.Equal(t, int32(3), total)
+	assert.Equal(t, []string{"00000001", "00000002"}, ncs)
+
+	// 密码错误, 返回401
+	assert.NoError(t, New().GET(ts.URL+"/digest").SetDigestAuth("user", "bad").Code(&code).Do())
+	assert.Equal(t, 401, code)
+
+	// auth-int, body参与摘要计算
+	assert.NoError(t, New().SetDefaultDigestAuth("user", "password").
+		POST(ts.URL+"/digest-int").SetBody("hello digest").BindBody(&s).Code(&code).Do())
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "ok:hello digest", s)
+}
+
+func Test_Auth_DigestRetry(t *testing.T) {
+	var total int32
+	var ncs []string
+	router := setupAuth(t, &total, &ncs)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	s := ""
+	code := 0
+	err := POST(ts.URL+"/digest-int").
+		SetDigestAuth("user", "password").
+		SetJSON(H{"a": "b"}).
+		BindBody(&s).
+		Code(&code).
+		Filter().
+		Retry().
+		Attempt(3).
+		Do()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "ok:{\"a\":\"b\"}\n", s)
+
+	// 默认digest认证, 第一次发送之后失败, 重试时Authorization已经设置过了, 还是要重新计算nc
+	first := false
+	fail := func(next HandlerFunc) HandlerFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := next(req)
+			if first && err == nil && resp.StatusCode == 200 {
+				first = false
+				resp.Body.Close()
+				return nil, errors.New("first fail")
+			}
+			return resp, err
+		}
+	}
+
+	ncs = nil
+	g := New().Use(fail).SetDefaultDigestAuth("user", "password")
+	assert.NoError(t, g.POST(ts.URL+"/digest-int").SetBody("cache challenge").Code(&code).Do())
+	assert.Equal(t, 200, code)
+	assert.Equal(t, []string{"00000001"}, ncs)
+
+	first = true
+	err = g.POST(ts.URL + "/digest-int").
+		SetBody("retry").
+		BindBody(&s).
+		Code(&code).
+		Filter().
+		Retry().
+		Attempt(2).
+		WaitTime(time.Millisecond).
+		MaxWaitTime(time.Millisecond * 10).
+		Do()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "ok:retry", s)
+	assert.Equal(t, []string{"00000001", "00000002", "00000003"}, ncs)
+}
+
+// auth-int读body计算摘要之后, 发送的body不能是空的
+func Test_Auth_DigestReaderBody(t *testing.T) {
+	var total int32
+	var ncs []string
+	router := setupAuth(t, &total, &ncs)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	// 复用连接时Transport会通过GetBody自己重发一次, 掩盖问题
+	c := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	g := New(c).SetDefaultDigestAuth("user", "password")
+
+	s := ""
+	code := 0
+	for i := 0; i < 2; i++ {
+		err := g.POST(ts.URL + "/digest-int").SetBody(strings.NewReader("hello reader")).BindBody(&s).Code(&code).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, 200, code)
+		assert.Equal(t, "ok:hello reader", s)
+	}
+
+	// 只有第一次收到401
+	assert.Equal(t, int32(3), total)
+}
+
+// RFC 7616 3.9.1
+func Test_Auth_DigestRFC7616(t *testing.T) {
+	for _, tc := range []struct {
+		algorithm string
+		response  string
+	}{
+		{"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	} {
+		h := http.Header{}
+		h.Set("WWW-Authenticate", `Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=`+tc.algorithm+`, `+
+			`nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`)
+
+		c, ok := parseDigestChallenge(h)
+		assert.True(t, ok)
+		assert.Equal(t, "auth", c.qop)
+
+		req, _ := http.NewRequest("GET", "http://www.example.org/dir/index.html", nil)
+		a := &auth{typ: authDigest, user: "Mufasa", password: "Circle of Life"}
+		assert.NoError(t, c.authorizeWith(req, a, "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"))
+
+		p := parseAuthParams(req.Header.Get("Authorization")[7:])
+		assert.Equal(t, tc.response, p["response"], tc.algorithm)
+		assert.Equal(t, "00000001", p["nc"])
+		assert.Equal(t, "/dir/index.html", p["uri"])
+		assert.Equal(t, "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS", p["opaque"])
+	}
+
+	c := &digestChallenge{algorithm: "SHA-1", qop: "auth"}
+	req, _ := http.NewRequest("GET", "http://www.example.org/", nil)
+	assert.Equal(t, ErrDigestAlgorithm, c.authorize(req, &auth{}))
+}
@@ -0,0 +1,95 @@
+package gout
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/guonaihong/gout/decode"
+)
+
+var ErrUnknownContentType = errors.New("gout:cannot decode response Content-Type")
+
+// BindAuto 没有设置Accept时使用的默认值
+var DefaultAutoAccept = "application/json, application/xml;q=0.9, application/yaml;q=0.8, */*;q=0.1"
+
+// autoDecode 收到响应之后根据Content-Type选择解码器
+type autoDecode struct {
+	obj         interface{}
+	contentType string
+	empty       bool
+}
+
+// BindAuto 根据响应的Content-Type解析body, 支持json, xml, yaml, x-www-form-urlencoded
+// 以及RegisterCodec注册的类型
+// text/plain 按照BindBody的方式解析, 其他类型返回ErrUnknownContentType
+// 没有设置Accept时发送DefaultAutoAccept
+func (df *DataFlow) BindAuto(obj interface{}) *DataFlow {
+	df.Req.bodyDecoder = &autoDecode{obj: obj}
+	return df
+}
+
+// newDecoder 根据Content-Type返回解码器, 还有debug打印body用的格式
+func newDecoder(contentType string, obj interface{}) (Decoder, string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w %q: %v", ErrUnknownContentType, contentType, err)
+	}
+
+	// RegisterCodec注册的类型优先
+	if e := getCodecByMIME(mediaType); e != nil {
+		return newCodecDecode(e.name, obj), e.name, nil
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return newCodecDecode("json", obj), "json", nil
+	case mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return newCodecDecode("xml", obj), "xml", nil
+	case mediaType == "application/x-yaml" || mediaType == "text/yaml" || mediaType == "text/x-yaml":
+		return newCodecDecode("yaml", obj), "yaml", nil
+	case mediaType == "application/x-www-form-urlencoded":
+		return decode.NewWWWFormDecode(obj), "", nil
+	case mediaType == "text/plain":
+		return decode.NewBodyDecode(obj), "", nil
+	}
+
+	return nil, "", fmt.Errorf("%w %q", ErrUnknownContentType, contentType)
+}
+
+func (a *autoDecode) Decode(r io.Reader) error {
+	if a.empty {
+		return nil
+	}
+
+	d, _, err := newDecoder(a.contentType, a.obj)
+	if err != nil {
+		return err
+	}
+
+	return d.Decode(r)
+}
+
+// setAutoAccept 请求里没有Accept时, 告诉服务端支持哪些格式
+func (r *Req) setAutoAccept(req *http.Request) {
+	if _, ok := r.bodyDecoder.(*autoDecode); ok && req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", DefaultAutoAccept)
+	}
+}
+
+// setAutoContentType 收到响应之后记下Content-Type
+func (r *Req) setAutoContentType(resp *http.Response) {
+	a, ok := r.bodyDecoder.(*autoDecode)
+	if !ok {
+		return
+	}
+
+	a.contentType = resp.Header.Get("Content-Type")
+	a.empty = resp.ContentLength == 0 || resp.StatusCode == http.StatusNoContent
+	if _, typ, err := newDecoder(a.contentType, nil); err == nil && typ != "" {
+		r.opt.RspBodyType = typ
+	}
+}
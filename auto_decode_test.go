@@ -0,0 +1,85 @@
+package gout
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type testAuto struct {
+	Name string `json:"name" xml:"name" yaml:"name" form:"name"`
+	Age  int    `json:"age" xml:"age" yaml:"age" form:"age"`
+}
+
+func setupAuto(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	// 根据Accept返回不同的格式
+	router.GET("/accept", func(c *gin.Context) {
+		obj := testAuto{Name: "gout", Age: 3}
+		accept := c.GetHeader("Accept")
+		switch {
+		case strings.HasPrefix(accept, "application/json"):
+			c.JSON(200, obj)
+		case strings.HasPrefix(accept, "application/xml"):
+			c.XML(200, obj)
+		case strings.HasPrefix(accept, "application/yaml"):
+			c.Data(200, "application/yaml; charset=utf-8", []byte("name: gout\nage: 3\n"))
+		case strings.HasPrefix(accept, "application/x-www-form-urlencoded"):
+			c.Data(200, "application/x-www-form-urlencoded", []byte("name=gout&age=3"))
+		case strings.HasPrefix(accept, "application/problem+json"):
+			c.Data(200, "application/problem+json", []byte(`{"name":"gout","age":3}`))
+		default:
+			c.Data(200, "application/octet-stream", []byte("gout"))
+		}
+	})
+
+	router.GET("/text", func(c *gin.Context) {
+		c.String(200, "hello")
+	})
+
+	router.GET("/empty", func(c *gin.Context) {
+		c.Status(204)
+	})
+
+	return router
+}
+
+func Test_BindAuto(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(setupAuto(t).ServeHTTP))
+	defer ts.Close()
+
+	for _, accept := range []string{
+		"application/xml",
+		"application/yaml",
+		"application/x-www-form-urlencoded",
+		"application/problem+json",
+	} {
+		got := testAuto{}
+		err := GET(ts.URL + "/accept").SetHeader(H{"Accept": accept}).BindAuto(&got).Do()
+		assert.NoError(t, err, accept)
+		assert.Equal(t, testAuto{Name: "gout", Age: 3}, got, accept)
+	}
+
+	// 没有设置Accept时发送DefaultAutoAccept, 服务端返回json
+	got := testAuto{}
+	assert.NoError(t, GET(ts.URL+"/accept").BindAuto(&got).Do())
+	assert.Equal(t, testAuto{Name: "gout", Age: 3}, got)
+
+	s := ""
+	assert.NoError(t, GET(ts.URL+"/text").BindAuto(&s).Do())
+	assert.Equal(t, "hello", s)
+
+	code := 0
+	assert.NoError(t, GET(ts.URL+"/empty").BindAuto(&got).Code(&code).Do())
+	assert.Equal(t, 204, code)
+
+	err := GET(ts.URL + "/accept").SetHeader(H{"Accept": "image/png"}).BindAuto(&got).Do()
+	assert.True(t, errors.Is(err, ErrUnknownContentType))
+	assert.Contains(t, err.Error(), "application/octet-stream")
+}
@@ -39,11 +39,26 @@ func (b *Bench) Do() error {
 		return err
 	}
 
+	// 压测会并发发送同一个body, 流式的body先读到内存里
+	if err = bufferBody(req); err != nil {
+		return err
+	}
+
+	if err = b.df.out.transport.err; err != nil {
+		return err
+	}
+
 	client := b.df.out.Client
 	if client == &DefaultClient {
 		client = &DefaultBenchClient
 	}
 
+	if r := b.df.Req.route; r != nil {
+		if client, err = b.df.out.routeClient(client, r); err != nil {
+			return err
+		}
+	}
+
 	r := bench.NewReport(context.Background(),
 		b.Task.Concurrent,
 		b.Task.Number,
@@ -0,0 +1,218 @@
+package gout
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/guonaihong/gout/encode"
+)
+
+// setBytesBody 和http.NewRequest处理[]byte的方式一致
+func setBytesBody(req *http.Request, buf []byte) {
+	req.ContentLength = int64(len(buf))
+	if len(buf) == 0 {
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		return
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf)), nil
+	}
+}
+
+// setReaderBody 直接把io.Reader当作http body发送, 不经过内存缓冲
+// 长度已知时设置Content-Length, 可以Seek时支持GetBody(重试, 重定向会用到)
+func setReaderBody(req *http.Request, r io.Reader) error {
+	// 和http.NewRequest一样, *bytes.Buffer直接使用未读的数据
+	if b, ok := r.(*bytes.Buffer); ok {
+		setBytesBody(req, b.Bytes())
+		return nil
+	}
+
+	req.ContentLength = -1
+	if l, ok := r.(interface{ Len() int }); ok {
+		req.ContentLength = int64(l.Len())
+	}
+
+	seeker, canSeek := r.(io.Seeker)
+	var offset int64
+	if canSeek {
+		var err error
+		if offset, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			// 比如管道, 终端这类不能Seek的文件
+			canSeek = false
+		}
+	}
+
+	if fd, ok := r.(*os.File); ok && canSeek {
+		fi, err := fd.Stat()
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode().IsRegular() {
+			req.ContentLength = fi.Size() - offset
+		}
+	}
+
+	if req.ContentLength == 0 {
+		setBytesBody(req, nil)
+		return nil
+	}
+
+	// 每次GetBody返回独立的io.Reader, 签名, digest这些通过GetBody读body的地方不会读走要发送的body
+	if ra, ok := r.(io.ReaderAt); ok && canSeek {
+		size := req.ContentLength
+		if size < 0 {
+			size = math.MaxInt64 - offset
+		}
+
+		newBody := func() io.ReadCloser {
+			return ioutil.NopCloser(io.NewSectionReader(ra, offset, size))
+		}
+
+		req.Body = newBody()
+		req.GetBody = func() (io.ReadCloser, error) { return newBody(), nil }
+		return nil
+	}
+
+	// 不关闭调用方传进来的io.Reader, 下次GetBody还要用
+	req.Body = ioutil.NopCloser(r)
+	if canSeek {
+		req.GetBody = func() (io.ReadCloser, error) {
+			if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(r), nil
+		}
+	}
+
+	return nil
+}
+
+// resetBody 通过GetBody读过body之后, 重新设置要发送的req.Body
+// 可以Seek但不是io.ReaderAt的body, GetBody返回的是同一个io.Reader, 需要重新Seek
+func resetBody(req *http.Request) error {
+	if req.GetBody == nil || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+
+	req.Body.Close()
+	req.Body = body
+	return nil
+}
+
+// pipeBody 第一次Read时才启动编码的goroutine, 边编码边发送
+// 请求没有发出去就被Close时, 不会泄漏goroutine
+type pipeBody struct {
+	once   sync.Once
+	pr     *io.PipeReader
+	pw     *io.PipeWriter
+	encode func(w io.Writer) error
+}
+
+func newPipeBody(encode func(w io.Writer) error) *pipeBody {
+	pr, pw := io.Pipe()
+	return &pipeBody{pr: pr, pw: pw, encode: encode}
+}
+
+func (p *pipeBody) Read(b []byte) (int, error) {
+	p.once.Do(func() {
+		go func() {
+			p.pw.CloseWithError(p.encode(p.pw))
+		}()
+	})
+
+	return p.pr.Read(b)
+}
+
+// Close 之后写端会返回io.ErrClosedPipe, 编码的goroutine随之退出
+// 还没有启动的goroutine也不会再启动
+func (p *pipeBody) Close() error {
+	p.once.Do(func() {})
+	return p.pr.Close()
+}
+
+// setFormBody form-data里的文件边读边发送, 不会整个读到内存
+func setFormBody(req *http.Request, obj interface{}, progress func(Progress)) error {
+	mw := multipart.NewWriter(nil)
+	boundary := mw.Boundary()
+
+	// 先计算一次长度, 同时也提前发现编码错误
+	size, err := encode.FormSize(obj, boundary)
+	if err != nil {
+		return err
+	}
+
+	newBody := func(progress func(Progress)) io.ReadCloser {
+		return newPipeBody(func(w io.Writer) error {
+			var fp *formProgress
+			if progress != nil {
+				fp = &formProgress{w: w, p: Progress{Total: size, PartTotal: -1}, cb: progress}
+				w = fp
+			}
+
+			f := encode.NewFormEncode(w)
+			if fp != nil {
+				f.PartWriter = fp.partWriter
+			}
+
+			if err := f.SetBoundary(boundary); err != nil {
+				return err
+			}
+
+			if err := encode.Encode(obj, f); err != nil {
+				return err
+			}
+
+			return f.End()
+		})
+	}
+
+	getBody := func() (io.ReadCloser, error) {
+		if progress == nil {
+			return newBody(nil), nil
+		}
+
+		return &formProgressBody{
+			ReadCloser: newBody(progress),
+			raw:        func() io.ReadCloser { return newBody(nil) },
+		}, nil
+	}
+
+	req.ContentLength = size
+	req.Body, _ = getBody()
+	req.GetBody = getBody
+
+	req.Header.Add("Content-Type", mw.FormDataContentType())
+	return nil
+}
+
+// bufferBody 把流式的body读到内存里, 压测这类需要并发发送同一个body的场景使用
+func bufferBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	all, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	setBytesBody(req, all)
+	return nil
+}
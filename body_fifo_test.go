@@ -0,0 +1,49 @@
+//go:build !windows
+// +build !windows
+
+package gout
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/guonaihong/gout/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// 管道的大小是0, 用chunked发送
+func Test_Body_FormFIFO(t *testing.T) {
+	router := setupBody(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "gout-fifo")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "voice.fifo")
+	assert.NoError(t, syscall.Mkfifo(path, 0600))
+
+	go func() {
+		fd, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		defer fd.Close()
+		fd.Write([]byte("fifo data"))
+	}()
+
+	var rsp testBodyRsp
+	err = POST(ts.URL + "/form").
+		SetForm(H{"mode": "A", "voice": core.FormFile(path)}).
+		BindJSON(&rsp).
+		Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "A:fifo data", rsp.Body)
+	assert.Equal(t, int64(-1), rsp.ContentLength)
+}
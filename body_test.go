@@ -0,0 +1,189 @@
+package gout
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guonaihong/gout/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type testBodyRsp struct {
+	ContentLength int64  `json:"content_length"`
+	Chunked       bool   `json:"chunked"`
+	Body          string `json:"body"`
+}
+
+func setupBody(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	router.POST("/body", func(c *gin.Context) {
+		all, err := ioutil.ReadAll(c.Request.Body)
+		assert.NoError(t, err)
+
+		chunked := len(c.Request.TransferEncoding) > 0 && c.Request.TransferEncoding[0] == "chunked"
+		c.JSON(200, testBodyRsp{ContentLength: c.Request.ContentLength, Chunked: chunked, Body: string(all)})
+	})
+
+	router.POST("/form", func(c *gin.Context) {
+		voice, err := c.FormFile("voice")
+		assert.NoError(t, err)
+
+		fd, err := voice.Open()
+		assert.NoError(t, err)
+		defer fd.Close()
+
+		all, err := ioutil.ReadAll(fd)
+		assert.NoError(t, err)
+
+		c.JSON(200, testBodyRsp{ContentLength: c.Request.ContentLength, Body: c.PostForm("mode") + ":" + string(all)})
+	})
+
+	return router
+}
+
+func Test_Body_File(t *testing.T) {
+	router := setupBody(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	fd, err := ioutil.TempFile("", "gout-body")
+	assert.NoError(t, err)
+	defer os.Remove(fd.Name())
+	defer fd.Close()
+
+	_, err = fd.WriteString("hello file body")
+	assert.NoError(t, err)
+
+	// 从文件中间开始发送
+	_, err = fd.Seek(6, io.SeekStart)
+	assert.NoError(t, err)
+
+	var rsp testBodyRsp
+	err = POST(ts.URL + "/body").SetBody(fd).BindJSON(&rsp).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, testBodyRsp{ContentLength: 9, Body: "file body"}, rsp)
+}
+
+func Test_Body_Reader(t *testing.T) {
+	router := setupBody(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	// 长度未知, 使用chunked发送
+	var rsp testBodyRsp
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("pipe body"))
+		pw.Close()
+	}()
+
+	err := POST(ts.URL + "/body").SetBody(pr).BindJSON(&rsp).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, testBodyRsp{ContentLength: -1, Chunked: true, Body: "pipe body"}, rsp)
+
+	// 长度已知
+	for _, r := range []io.Reader{
+		strings.NewReader("reader body"),
+		bytes.NewReader([]byte("reader body")),
+		bytes.NewBufferString("reader body"),
+	} {
+		rsp = testBodyRsp{}
+		err = POST(ts.URL + "/body").SetBody(r).BindJSON(&rsp).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, testBodyRsp{ContentLength: 11, Body: "reader body"}, rsp)
+	}
+}
+
+func Test_Body_GetBody(t *testing.T) {
+	req, err := http.NewRequest("POST", "/", nil)
+	assert.NoError(t, err)
+
+	r := strings.NewReader("seek body")
+	assert.NoError(t, setReaderBody(req, r))
+
+	for i := 0; i < 2; i++ {
+		body, err := req.GetBody()
+		assert.NoError(t, err)
+		all, err := ioutil.ReadAll(body)
+		assert.NoError(t, err)
+		assert.Equal(t, "seek body", string(all))
+	}
+
+	// 不能Seek, 没有GetBody
+	req, err = http.NewRequest("POST", "/", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, setReaderBody(req, &core.ReadCloseFail{}))
+	assert.Nil(t, req.GetBody)
+	assert.Equal(t, int64(-1), req.ContentLength)
+}
+
+func Test_Body_Form(t *testing.T) {
+	router := setupBody(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	need, err := ioutil.ReadFile("testdata/voice.pcm")
+	assert.NoError(t, err)
+
+	var rsp testBodyRsp
+	err = POST(ts.URL + "/form").
+		SetForm(H{"mode": "A", "voice": core.FormFile("testdata/voice.pcm")}).
+		BindJSON(&rsp).
+		Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "A:"+string(need), rsp.Body)
+	assert.NotEqual(t, int64(-1), rsp.ContentLength)
+
+	// 文件不存在, 请求不会发出
+	err = POST(ts.URL + "/form").SetForm(H{"voice": core.FormFile("testdata/not-found.pcm")}).Do()
+	assert.Error(t, err)
+}
+
+func Test_Body_PipeClose(t *testing.T) {
+	run := false
+	p := newPipeBody(func(w io.Writer) error {
+		run = true
+		return nil
+	})
+
+	// 没有读取就关闭, 编码函数不会运行
+	assert.NoError(t, p.Close())
+	_, err := p.Read(make([]byte, 1))
+	assert.Error(t, err)
+
+	p = newPipeBody(func(w io.Writer) error {
+		_, err := w.Write([]byte("pipe"))
+		return err
+	})
+
+	all, err := ioutil.ReadAll(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "pipe", string(all))
+	assert.False(t, run)
+}
+
+func Test_Body_Buffer(t *testing.T) {
+	req, err := http.NewRequest("POST", "/", nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, setReaderBody(req, ioutil.NopCloser(strings.NewReader("buffer"))))
+	assert.Nil(t, req.GetBody)
+
+	assert.NoError(t, bufferBody(req))
+	assert.Equal(t, int64(6), req.ContentLength)
+	for i := 0; i < 2; i++ {
+		body, err := req.GetBody()
+		assert.NoError(t, err)
+		all, err := ioutil.ReadAll(body)
+		assert.NoError(t, err)
+		assert.Equal(t, "buffer", string(all))
+	}
+}
@@ -0,0 +1,49 @@
+package gout
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/guonaihong/gout/decode"
+	"github.com/guonaihong/gout/encode"
+)
+
+// debug打印时map解析成map[string]interface{}, 才能转成json
+var cborDebugMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+}.DecMode()
+
+type cborCodec struct{}
+
+func (cborCodec) Encode(w io.Writer, obj interface{}) error {
+	return encode.NewCBOREncode(obj).Encode(w)
+}
+
+func (cborCodec) Decode(r io.Reader, obj interface{}) error {
+	return decode.DecodeCBOR(r, obj)
+}
+
+func (cborCodec) DebugJSON(body []byte, obj interface{}) ([]byte, error) {
+	var v interface{}
+	if err := cborDebugMode.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+func init() {
+	RegisterCodec("cbor", "application/cbor", cborCodec{})
+}
+
+// SetCBOR 发送CBOR编码的body, Content-Type是application/cbor
+func (df *DataFlow) SetCBOR(obj interface{}) *DataFlow {
+	return df.SetCodec("cbor", obj)
+}
+
+// BindCBOR 解析CBOR编码的body
+func (df *DataFlow) BindCBOR(obj interface{}) *DataFlow {
+	return df.BindCodec("cbor", obj)
+}
@@ -0,0 +1,12 @@
+package gout
+
+import (
+	"testing"
+)
+
+func Test_CBOR(t *testing.T) {
+	testBinaryCodecDo(t,
+		func(df *DataFlow, obj interface{}) *DataFlow { return df.SetCBOR(obj) },
+		func(df *DataFlow, obj interface{}) *DataFlow { return df.BindCBOR(obj) },
+		"application/cbor")
+}
@@ -0,0 +1,218 @@
+package gout
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/guonaihong/gout/decode"
+	"github.com/guonaihong/gout/encode"
+)
+
+var ErrUnknownCodec = errors.New("gout:unknown codec")
+
+// Codec 自定义的body格式, 通过RegisterCodec注册
+type Codec interface {
+	Encode(w io.Writer, obj interface{}) error
+	Decode(r io.Reader, obj interface{}) error
+}
+
+// CodecDebugger 可选, 二进制格式实现它之后debug模式会把body转成json打印
+// obj是SetCodec, BindCodec这类方法传入的对象, 可能为nil
+type CodecDebugger interface {
+	DebugJSON(body []byte, obj interface{}) ([]byte, error)
+}
+
+type codecEntry struct {
+	name     string
+	mimeType string
+	codec    Codec
+}
+
+var codecs = struct {
+	sync.RWMutex
+	byName map[string]*codecEntry
+	byMIME map[string]*codecEntry
+}{
+	byName: make(map[string]*codecEntry),
+	byMIME: make(map[string]*codecEntry),
+}
+
+// RegisterCodec 注册name对应的编解码器和Content-Type
+// 可以替换内置的json, xml, yaml, SetJSON, BindJSON这些方法都会使用新注册的codec
+// BindAuto收到mimeType的响应时也会使用它
+func RegisterCodec(name, mimeType string, c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	if old, ok := codecs.byName[name]; ok && codecs.byMIME[old.mimeType] == old {
+		delete(codecs.byMIME, old.mimeType)
+	}
+
+	e := &codecEntry{name: name, mimeType: mimeType, codec: c}
+	codecs.byName[name] = e
+	codecs.byMIME[strings.ToLower(mimeType)] = e
+}
+
+func getCodec(name string) *codecEntry {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.byName[name]
+}
+
+func getCodecByMIME(mediaType string) *codecEntry {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.byMIME[mediaType]
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, obj interface{}) error {
+	return encode.NewJSONEncode(obj).Encode(w)
+}
+
+func (jsonCodec) Decode(r io.Reader, obj interface{}) error {
+	return decode.DecodeJSON(r, obj)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Encode(w io.Writer, obj interface{}) error {
+	return encode.NewXMLEncode(obj).Encode(w)
+}
+
+func (xmlCodec) Decode(r io.Reader, obj interface{}) error {
+	return decode.DecodeXML(r, obj)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Encode(w io.Writer, obj interface{}) error {
+	return encode.NewYAMLEncode(obj).Encode(w)
+}
+
+func (yamlCodec) Decode(r io.Reader, obj interface{}) error {
+	return decode.DecodeYAML(r, obj)
+}
+
+func init() {
+	RegisterCodec("json", "application/json", jsonCodec{})
+	RegisterCodec("xml", "application/xml", xmlCodec{})
+	RegisterCodec("yaml", "application/yaml", yamlCodec{})
+}
+
+// codecEncode 发送请求的时候才查找codec, 实现了Encoder
+type codecEncode struct {
+	name string
+	obj  interface{}
+}
+
+func newCodecEncode(name string, obj interface{}) Encoder {
+	if obj == nil {
+		return nil
+	}
+	return &codecEncode{name: name, obj: obj}
+}
+
+func (c *codecEncode) Encode(w io.Writer) error {
+	e := getCodec(c.name)
+	if e == nil {
+		return fmt.Errorf("%w %q", ErrUnknownCodec, c.name)
+	}
+	return e.codec.Encode(w, c.obj)
+}
+
+func (c *codecEncode) Name() string {
+	return c.name
+}
+
+// codecDecode 实现了Decoder
+type codecDecode struct {
+	name string
+	obj  interface{}
+}
+
+func newCodecDecode(name string, obj interface{}) Decoder {
+	if obj == nil {
+		return nil
+	}
+	return &codecDecode{name: name, obj: obj}
+}
+
+func (c *codecDecode) Decode(r io.Reader) error {
+	e := getCodec(c.name)
+	if e == nil {
+		return fmt.Errorf("%w %q", ErrUnknownCodec, c.name)
+	}
+	return e.codec.Decode(r, c.obj)
+}
+
+// SetCodec 使用RegisterCodec注册的name编码body, 并设置对应的Content-Type
+func (df *DataFlow) SetCodec(name string, obj interface{}) *DataFlow {
+	df.Req.opt.ReqBodyType = name
+	df.Req.bodyEncoder = newCodecEncode(name, obj)
+	return df
+}
+
+// BindCodec 使用RegisterCodec注册的name解析body
+func (df *DataFlow) BindCodec(name string, obj interface{}) *DataFlow {
+	df.Req.opt.RspBodyType = name
+	df.Req.bodyDecoder = newCodecDecode(name, obj)
+	return df
+}
+
+// codecContentType 返回codec注册的Content-Type
+func codecContentType(name string) (string, bool) {
+	e := getCodec(name)
+	if e == nil {
+		return "", false
+	}
+	return e.mimeType, true
+}
+
+// codecToJSON debug打印时把自定义格式的body转成json, 方便着色
+// 实现了CodecDebugger的codec总是转换, 其他的只在打开颜色时转换
+// 内置的json, xml, yaml和转换失败的body原样返回
+func codecToJSON(r io.Reader, name string, obj interface{}, color bool) (io.Reader, string) {
+	switch name {
+	case "", "json", "xml", "yaml":
+		return r, name
+	}
+
+	e := getCodec(name)
+	if e == nil {
+		return r, name
+	}
+
+	debugger, ok := e.codec.(CodecDebugger)
+	if !ok && !color {
+		return r, name
+	}
+
+	all, err := ioutil.ReadAll(r)
+	if err != nil {
+		return bytes.NewReader(all), name
+	}
+
+	var js []byte
+	if ok {
+		js, err = debugger.DebugJSON(all, obj)
+	} else {
+		var v interface{}
+		if err = e.codec.Decode(bytes.NewReader(all), &v); err == nil {
+			js, err = json.Marshal(v)
+		}
+	}
+
+	if err != nil {
+		return bytes.NewReader(all), name
+	}
+
+	return bytes.NewReader(js), "json"
+}
@@ -0,0 +1,159 @@
+package gout
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// kvCodec 每行一个key=value, 只支持map[string]string
+type kvCodec struct{}
+
+func (kvCodec) Encode(w io.Writer, obj interface{}) error {
+	m, ok := obj.(map[string]string)
+	if !ok {
+		return fmt.Errorf("kv:unsupported type %T", obj)
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", k, m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (kvCodec) Decode(r io.Reader, obj interface{}) error {
+	all, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	m := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(all)), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 {
+			m[kv[0]] = kv[1]
+		}
+	}
+
+	switch o := obj.(type) {
+	case *map[string]string:
+		*o = m
+	case *interface{}:
+		v := make(map[string]interface{}, len(m))
+		for k, s := range m {
+			v[k] = s
+		}
+		*o = v
+	default:
+		return fmt.Errorf("kv:unsupported type %T", obj)
+	}
+	return nil
+}
+
+// strictJSON 不允许未知字段
+type strictJSON struct{}
+
+func (strictJSON) Encode(w io.Writer, obj interface{}) error {
+	return json.NewEncoder(w).Encode(obj)
+}
+
+func (strictJSON) Decode(r io.Reader, obj interface{}) error {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	return d.Decode(obj)
+}
+
+func setupCodec(t *testing.T) *gin.Engine {
+	router := gin.New()
+	router.POST("/echo", func(c *gin.Context) {
+		all, err := c.GetRawData()
+		assert.NoError(t, err)
+		c.Data(200, c.GetHeader("Content-Type"), all)
+	})
+	return router
+}
+
+func Test_Codec_Custom(t *testing.T) {
+	RegisterCodec("kv", "application/x-kv", kvCodec{})
+
+	ts := httptest.NewServer(http.HandlerFunc(setupCodec(t).ServeHTTP))
+	defer ts.Close()
+
+	var got map[string]string
+	var buf bytes.Buffer
+	err := POST(ts.URL+"/echo").
+		SetCodec("kv", map[string]string{"b": "2", "a": "1"}).
+		BindCodec("kv", &got).
+		Debug(DebugFunc(func(o *DebugOption) {
+			o.Debug = true
+			o.Color = true
+			o.Write = &buf
+		})).
+		Do()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, got)
+
+	// debug打印时转成json着色
+	assert.NotContains(t, buf.String(), "a=1")
+	assert.Contains(t, buf.String(), "application/x-kv")
+
+	// BindAuto根据注册的Content-Type选择codec
+	got = nil
+	err = POST(ts.URL+"/echo").SetCodec("kv", map[string]string{"c": "3"}).BindAuto(&got).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"c": "3"}, got)
+
+	err = POST(ts.URL+"/echo").SetCodec("not-found", "x").Do()
+	assert.True(t, errors.Is(err, ErrUnknownCodec))
+}
+
+func Test_Codec_ReplaceJSON(t *testing.T) {
+	RegisterCodec("json", "application/json", strictJSON{})
+	defer RegisterCodec("json", "application/json", jsonCodec{})
+
+	ts := httptest.NewServer(http.HandlerFunc(setupCodec(t).ServeHTTP))
+	defer ts.Close()
+
+	var got struct {
+		A string `json:"a"`
+	}
+
+	err := POST(ts.URL + "/echo").SetJSON(H{"a": "1", "b": "2"}).BindJSON(&got).Do()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown field")
+
+	err = POST(ts.URL + "/echo").SetJSON(H{"a": "1"}).BindJSON(&got).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "1", got.A)
+}
+
+func Test_Codec_YAMLContentType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(setupCodec(t).ServeHTTP))
+	defer ts.Close()
+
+	var header struct {
+		ContentType string `header:"content-type"`
+	}
+	err := POST(ts.URL + "/echo").SetYAML(H{"a": "1"}).BindHeader(&header).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "application/yaml", header.ContentType)
+}
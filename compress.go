@@ -0,0 +1,237 @@
+package gout
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressGzip    = "gzip"
+	CompressDeflate = "deflate"
+	CompressZstd    = "zstd"
+)
+
+var ErrCompressEncoding = errors.New("gout:unsupported compress encoding")
+
+// compressOpt 请求body的压缩设置
+type compressOpt struct {
+	encoding string
+	minSize  int
+}
+
+// SetCompress 压缩SetJSON, SetBody等设置的body, 并设置Content-Encoding
+// encoding 可以是gzip, deflate, zstd. body小于minSize个字节时不压缩
+// SetBody传入的io.Reader边读边压缩, 长度未知时总是压缩. form-data不压缩
+func (df *DataFlow) SetCompress(encoding string, minSize int) *DataFlow {
+	df.Req.compress = &compressOpt{encoding: encoding, minSize: minSize}
+	return df
+}
+
+// SetDefaultCompress 设置默认的body压缩, 请求里的SetCompress会覆盖它
+func (g *Gout) SetDefaultCompress(encoding string, minSize int) *Gout {
+	g.defCompress = &compressOpt{encoding: encoding, minSize: minSize}
+	return g
+}
+
+func (r *Req) getCompress() *compressOpt {
+	if r.compress != nil {
+		return r.compress
+	}
+
+	if r.g != nil {
+		return r.g.defCompress
+	}
+
+	return nil
+}
+
+// compressBody 压缩已经编码好的body, 返回压缩之后的数据
+func (r *Req) compressBody(req *http.Request, body []byte) ([]byte, error) {
+	c := r.getCompress()
+	if c == nil || len(body) == 0 || len(body) < c.minSize {
+		return body, nil
+	}
+
+	var buf bytes.Buffer
+	w, err := newCompressWriter(c.encoding, &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(body); err != nil {
+		return nil, err
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Encoding", c.encoding)
+	return buf.Bytes(), nil
+}
+
+// compressReaderBody 通过pipeBody边读边压缩SetBody传入的io.Reader
+func (r *Req) compressReaderBody(req *http.Request) error {
+	c := r.getCompress()
+	if c == nil || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	if req.ContentLength > 0 && req.ContentLength < int64(c.minSize) {
+		return nil
+	}
+
+	switch c.encoding {
+	case CompressGzip, CompressDeflate, CompressZstd:
+	default:
+		return ErrCompressEncoding
+	}
+
+	wrap := func(src io.ReadCloser) io.ReadCloser {
+		return newPipeBody(func(w io.Writer) error {
+			defer src.Close()
+
+			cw, err := newCompressWriter(c.encoding, w)
+			if err != nil {
+				return err
+			}
+
+			if _, err = io.Copy(cw, src); err != nil {
+				return err
+			}
+			return cw.Close()
+		})
+	}
+
+	req.Body = wrap(req.Body)
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return wrap(body), nil
+		}
+	}
+
+	// 压缩之后的长度未知, 使用chunked发送
+	req.ContentLength = -1
+	req.Header.Set("Content-Encoding", c.encoding)
+	return nil
+}
+
+func newCompressWriter(encoding string, w io.Writer) (io.WriteCloser, error) {
+	switch encoding {
+	case CompressGzip:
+		return gzip.NewWriter(w), nil
+	case CompressDeflate:
+		return zlib.NewWriter(w), nil
+	case CompressZstd:
+		return zstd.NewWriter(w)
+	}
+
+	return nil, ErrCompressEncoding
+}
+
+// zstdReader *zstd.Decoder的Close没有返回值
+type zstdReader struct {
+	*zstd.Decoder
+}
+
+func (z zstdReader) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+// newDecompressReader 根据Content-Encoding返回解压的io.ReadCloser, 不支持的编码返回false
+func newDecompressReader(encoding string, r io.Reader) (io.ReadCloser, bool, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		rc, err := gzip.NewReader(r)
+		return rc, true, err
+	case "deflate":
+		return newDeflateReader(r), true, nil
+	case "br":
+		return ioutil.NopCloser(brotli.NewReader(r)), true, nil
+	case "zstd":
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, true, err
+		}
+		return zstdReader{d}, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// deflate按照RFC 7230是zlib格式, 有些服务端发送的是没有zlib头的deflate数据
+func newDeflateReader(r io.Reader) io.ReadCloser {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(2)
+	if len(head) == 2 && head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+		if rc, err := zlib.NewReader(br); err == nil {
+			return rc
+		}
+	}
+
+	return flate.NewReader(br)
+}
+
+// decompressResponse 解压Content-Encoding是gzip, deflate, br, zstd的响应
+// Transport自动解压gzip时已经去掉了Content-Encoding, 这里不会重复解压
+func decompressResponse(resp *http.Response) error {
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding == "" || resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+
+	rc, ok, err := newDecompressReader(encoding, resp.Body)
+	if !ok {
+		return nil
+	}
+
+	if err != nil {
+		// 空body
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	resp.Body = &decompressBody{ReadCloser: rc, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// decompressBody 读到EOF时释放解压器(zstd会启动goroutine), 关闭时同时关闭原始的body
+type decompressBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+func (d *decompressBody) Read(p []byte) (int, error) {
+	n, err := d.ReadCloser.Read(p)
+	if err == io.EOF {
+		d.ReadCloser.Close()
+	}
+	return n, err
+}
+
+func (d *decompressBody) Close() error {
+	d.ReadCloser.Close()
+	return d.raw.Close()
+}
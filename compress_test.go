@@ -0,0 +1,148 @@
+package gout
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCompress(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		w, _ = zstd.NewWriter(&buf)
+	}
+
+	_, err := w.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func setupCompress(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	// 解压请求body, 原样返回
+	router.POST("/echo", func(c *gin.Context) {
+		encoding := c.GetHeader("Content-Encoding")
+		var r io.Reader = c.Request.Body
+		if encoding != "" {
+			rc, ok, err := newDecompressReader(encoding, r)
+			assert.True(t, ok)
+			assert.NoError(t, err)
+			r = rc
+		}
+
+		all, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		c.String(200, encoding+"|"+string(all))
+	})
+
+	router.GET("/encoded/:encoding", func(c *gin.Context) {
+		encoding := c.Param("encoding")
+		body := testCompress(t, encoding, []byte(`{"name":"gout"}`))
+		if encoding == "raw-deflate" {
+			encoding = "deflate"
+		}
+
+		c.Header("Content-Encoding", encoding)
+		c.Data(200, "application/json", body)
+	})
+
+	return router
+}
+
+func Test_Compress_Request(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(setupCompress(t).ServeHTTP))
+	defer ts.Close()
+
+	data := strings.Repeat("gout", 100)
+	for _, encoding := range []string{CompressGzip, CompressDeflate, CompressZstd} {
+		s := ""
+		err := POST(ts.URL+"/echo").SetBody(data).SetCompress(encoding, 0).BindBody(&s).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, encoding+"|"+data, s)
+	}
+
+	// 小于阈值不压缩
+	s := ""
+	g := New().SetDefaultCompress(CompressGzip, 1024)
+	assert.NoError(t, g.POST(ts.URL+"/echo").SetJSON(H{"a": "b"}).BindBody(&s).Do())
+	assert.Equal(t, "|{\"a\":\"b\"}\n", s)
+
+	assert.NoError(t, g.POST(ts.URL+"/echo").SetBody(data).SetCompress(CompressZstd, 10).BindBody(&s).Do())
+	assert.Equal(t, "zstd|"+data, s)
+
+	err := POST(ts.URL+"/echo").SetBody(data).SetCompress("lz4", 0).Do()
+	assert.Equal(t, ErrCompressEncoding, err)
+
+	// io.Reader边读边压缩, 长度未知时总是压缩
+	big := strings.Repeat("gout", 512)
+	for body, want := range map[io.Reader]string{
+		strings.NewReader(big):                       big,
+		struct{ io.Reader }{strings.NewReader(data)}: data,
+	} {
+		err = g.POST(ts.URL + "/echo").SetBody(body).BindBody(&s).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, "gzip|"+want, s)
+	}
+
+	err = g.POST(ts.URL + "/echo").SetBody(strings.NewReader("small")).BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "|small", s)
+
+	err = POST(ts.URL+"/echo").SetBody(strings.NewReader(data)).SetCompress("lz4", 0).Do()
+	assert.Equal(t, ErrCompressEncoding, err)
+}
+
+func Test_Compress_Response(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(setupCompress(t).ServeHTTP))
+	defer ts.Close()
+
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate", "br", "zstd"} {
+		var obj struct {
+			Name string `json:"name"`
+		}
+
+		var buf bytes.Buffer
+		err := GET(ts.URL + "/encoded/" + encoding).
+			SetHeader(H{"Accept-Encoding": "gzip, deflate, br, zstd"}).
+			Debug(DebugFunc(func(o *DebugOption) {
+				o.Debug = true
+				o.Write = &buf
+			})).
+			BindJSON(&obj).
+			Do()
+		assert.NoError(t, err, encoding)
+		assert.Equal(t, "gout", obj.Name, encoding)
+		assert.Contains(t, buf.String(), `"gout"`, encoding)
+
+		rsp, err := GET(ts.URL + "/encoded/" + encoding).
+			SetHeader(H{"Accept-Encoding": "gzip, deflate, br, zstd"}).
+			DoResponse()
+		assert.NoError(t, err, encoding)
+		assert.Equal(t, `{"name":"gout"}`, string(rsp.Body))
+		assert.Equal(t, "", rsp.Header.Get("Content-Encoding"))
+	}
+}
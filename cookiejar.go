@@ -0,0 +1,467 @@
+package gout
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 保存cookie的文件格式
+type CookieFormat int
+
+const (
+	CookieJSON     CookieFormat = iota
+	CookieNetscape              // curl -c/-b 使用的格式
+)
+
+var _ http.CookieJar = (*CookieJar)(nil)
+
+var ErrNoCookieJar = errors.New("gout:client has no *CookieJar, use NewSession")
+
+// CookieJar 实现了http.CookieJar, 可以保存到文件以及从文件加载
+// 没有公共后缀列表(public suffix list)时, 只拒绝没有点的domain(比如com)
+type CookieJar struct {
+	mu      sync.Mutex
+	entries map[string]*jarEntry
+	seq     uint64
+	psList  cookiejar.PublicSuffixList
+}
+
+type jarEntry struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires,omitempty"` // 零值表示会话cookie
+	Secure   bool      `json:"secure"`
+	HttpOnly bool      `json:"http_only"`
+	HostOnly bool      `json:"host_only"`
+
+	seq uint64 // 创建顺序, 同样长度的path先创建的排在前面
+}
+
+// NewCookieJar psList和net/http/cookiejar的一样, 可以使用golang.org/x/net/publicsuffix.List
+func NewCookieJar(psList ...cookiejar.PublicSuffixList) *CookieJar {
+	j := &CookieJar{entries: make(map[string]*jarEntry)}
+	if len(psList) > 0 {
+		j.psList = psList[0]
+	}
+	return j
+}
+
+// isPublicSuffix co.uk, com这类公共后缀不能作为cookie的domain
+// 否则a.example.com可以给所有.com网站设置cookie
+func (j *CookieJar) isPublicSuffix(domain string) bool {
+	if j.psList != nil {
+		return j.psList.PublicSuffix(domain) == domain
+	}
+
+	return !strings.Contains(domain, ".")
+}
+
+func (e *jarEntry) id() string {
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+func (e *jarEntry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !e.Expires.After(now)
+}
+
+func (e *jarEntry) domainMatch(host string) bool {
+	if e.Domain == host {
+		return true
+	}
+
+	return !e.HostOnly && strings.HasSuffix(host, "."+e.Domain)
+}
+
+// RFC 6265 5.1.4
+func (e *jarEntry) pathMatch(path string) bool {
+	if path == e.Path {
+		return true
+	}
+
+	if e.Path != "" && strings.HasPrefix(path, e.Path) {
+		return e.Path[len(e.Path)-1] == '/' || path[len(e.Path)] == '/'
+	}
+
+	return false
+}
+
+func canonicalHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// RFC 6265 5.1.4 默认path
+func defaultPath(path string) string {
+	if len(path) == 0 || path[0] != '/' {
+		return "/"
+	}
+
+	i := strings.LastIndex(path, "/")
+	if i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host := canonicalHost(u.Host)
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, c := range cookies {
+		e := &jarEntry{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+
+		if domain := strings.ToLower(strings.TrimPrefix(c.Domain, ".")); len(domain) == 0 {
+			e.Domain, e.HostOnly = host, true
+		} else {
+			// IP地址只能是完全一样
+			if domain != host && (net.ParseIP(host) != nil || !strings.HasSuffix(host, "."+domain)) {
+				continue
+			}
+
+			e.Domain = domain
+			if net.ParseIP(host) == nil && j.isPublicSuffix(domain) {
+				// 和net/http/cookiejar一样, 公共后缀本身的站点可以设置, 当作host-only
+				if domain != host {
+					continue
+				}
+				e.HostOnly = true
+			}
+		}
+
+		if len(e.Path) == 0 || e.Path[0] != '/' {
+			e.Path = defaultPath(u.Path)
+		}
+
+		switch {
+		case c.MaxAge < 0:
+			e.Expires = now
+		case c.MaxAge > 0:
+			e.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			e.Expires = c.Expires
+		}
+
+		j.set(e, now)
+	}
+}
+
+// 需要持有锁
+func (j *CookieJar) set(e *jarEntry, now time.Time) {
+	id := e.id()
+	if e.expired(now) {
+		delete(j.entries, id)
+		return
+	}
+
+	if old, ok := j.entries[id]; ok {
+		e.seq = old.seq
+	} else {
+		j.seq++
+		e.seq = j.seq
+	}
+
+	j.entries[id] = e
+}
+
+func (j *CookieJar) Cookies(u *url.URL) (cookies []*http.Cookie) {
+	host := canonicalHost(u.Host)
+	path := u.Path
+	if len(path) == 0 {
+		path = "/"
+	}
+	secure := u.Scheme == "https" || u.Scheme == "wss"
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var selected []*jarEntry
+	for id, e := range j.entries {
+		if e.expired(now) {
+			delete(j.entries, id)
+			continue
+		}
+
+		if e.Secure && !secure {
+			continue
+		}
+
+		if e.domainMatch(host) && e.pathMatch(path) {
+			selected = append(selected, e)
+		}
+	}
+
+	// RFC 6265 5.4 path长的排在前面
+	sort.Slice(selected, func(a, b int) bool {
+		if len(selected[a].Path) != len(selected[b].Path) {
+			return len(selected[a].Path) > len(selected[b].Path)
+		}
+		return selected[a].seq < selected[b].seq
+	})
+
+	for _, e := range selected {
+		cookies = append(cookies, &http.Cookie{Name: e.Name, Value: e.Value})
+	}
+
+	return cookies
+}
+
+// All 返回所有没有过期的cookie
+func (j *CookieJar) All() (cookies []*http.Cookie) {
+	for _, e := range j.sortedEntries() {
+		cookies = append(cookies, &http.Cookie{
+			Name:     e.Name,
+			Value:    e.Value,
+			Domain:   e.Domain,
+			Path:     e.Path,
+			Expires:  e.Expires,
+			Secure:   e.Secure,
+			HttpOnly: e.HttpOnly,
+		})
+	}
+	return cookies
+}
+
+func (j *CookieJar) sortedEntries() []jarEntry {
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries := make([]jarEntry, 0, len(j.entries))
+	for _, e := range j.entries {
+		if !e.expired(now) {
+			entries = append(entries, *e)
+		}
+	}
+
+	sort.Slice(entries, func(a, b int) bool { return entries[a].seq < entries[b].seq })
+	return entries
+}
+
+// Save 把cookie写入w, 会话cookie也会保存
+func (j *CookieJar) Save(w io.Writer, format CookieFormat) error {
+	entries := j.sortedEntries()
+
+	switch format {
+	case CookieJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case CookieNetscape:
+		return writeNetscape(w, entries)
+	}
+
+	return fmt.Errorf("gout:unknown cookie format %d", format)
+}
+
+// Load 从r中读取cookie, 已经过期的和没有domain, name的会被忽略, path为空时使用"/"
+func (j *CookieJar) Load(r io.Reader, format CookieFormat) error {
+	var entries []jarEntry
+	var err error
+
+	switch format {
+	case CookieJSON:
+		err = json.NewDecoder(r).Decode(&entries)
+	case CookieNetscape:
+		entries, err = readNetscape(r)
+	default:
+		err = fmt.Errorf("gout:unknown cookie format %d", format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := range entries {
+		e := &entries[i]
+		if e.Domain == "" || e.Name == "" {
+			continue
+		}
+
+		// 文件里可能有以前保存的超级cookie
+		if !e.HostOnly && j.isPublicSuffix(e.Domain) {
+			continue
+		}
+
+		// 手写或者其他工具生成的文件可能没有path
+		if !strings.HasPrefix(e.Path, "/") {
+			e.Path = "/"
+		}
+		j.set(e, now)
+	}
+	return nil
+}
+
+func (j *CookieJar) SaveFile(path string, format CookieFormat) error {
+	fd, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err = j.Save(fd, format); err != nil {
+		fd.Close()
+		return err
+	}
+
+	return fd.Close()
+}
+
+func (j *CookieJar) LoadFile(path string, format CookieFormat) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	return j.Load(fd, format)
+}
+
+const httpOnlyPrefix = "#HttpOnly_"
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// domain \t includeSubdomains \t path \t secure \t expires \t name \t value
+func writeNetscape(w io.Writer, entries []jarEntry) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "# Netscape HTTP Cookie File\n\n")
+
+	for _, e := range entries {
+		domain := e.Domain
+		if !e.HostOnly {
+			domain = "." + domain
+		}
+
+		if e.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+
+		var expires int64
+		if !e.Expires.IsZero() {
+			expires = e.Expires.Unix()
+		}
+
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(!e.HostOnly), e.Path, netscapeBool(e.Secure), expires, e.Name, e.Value)
+	}
+
+	return bw.Flush()
+}
+
+func readNetscape(r io.Reader) (entries []jarEntry, err error) {
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = line[len(httpOnlyPrefix):]
+		}
+
+		if len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("gout:netscape cookie line %d: want 7 fields, got %d", lineNo, len(fields))
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("gout:netscape cookie line %d: %s", lineNo, err)
+		}
+
+		e := jarEntry{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+
+		if expires > 0 {
+			e.Expires = time.Unix(expires, 0)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, scanner.Err()
+}
+
+// NewSession 返回一个带CookieJar的Gout, 服务端返回的Set-Cookie会在后续请求中自动带上
+// 传入的http.Client会被复制一份, 不会修改调用者的Client
+func NewSession(c ...*http.Client) *Gout {
+	client := DefaultClient
+	if len(c) > 0 && c[0] != nil {
+		client = *c[0]
+	}
+
+	client.Jar = NewCookieJar()
+	return New(&client)
+}
+
+// CookieJar 返回session使用的CookieJar, 不是NewSession创建的返回nil
+func (g *Gout) CookieJar() *CookieJar {
+	jar, _ := g.Client.Jar.(*CookieJar)
+	return jar
+}
+
+// SaveCookies 把session里的cookie保存到文件
+func (g *Gout) SaveCookies(path string, format CookieFormat) error {
+	jar := g.CookieJar()
+	if jar == nil {
+		return ErrNoCookieJar
+	}
+
+	return jar.SaveFile(path, format)
+}
+
+// LoadCookies 从文件加载cookie到session
+func (g *Gout) LoadCookies(path string, format CookieFormat) error {
+	jar := g.CookieJar()
+	if jar == nil {
+		return ErrNoCookieJar
+	}
+
+	return jar.LoadFile(path, format)
+}
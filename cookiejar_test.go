@@ -0,0 +1,253 @@
+package gout
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCookieJar(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	router.POST("/login", func(c *gin.Context) {
+		c.SetCookie("sid", "sid-ok", 3600, "/", "", false, true)
+		c.String(200, "ok")
+	})
+
+	router.GET("/profile", func(c *gin.Context) {
+		sid, err := c.Cookie("sid")
+		if err != nil {
+			c.String(401, "no sid")
+			return
+		}
+		c.String(200, sid)
+	})
+
+	router.POST("/logout", func(c *gin.Context) {
+		c.SetCookie("sid", "", -1, "/", "", false, true)
+		c.String(200, "ok")
+	})
+
+	return router
+}
+
+func Test_CookieJar_Session(t *testing.T) {
+	router := setupCookieJar(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	s := NewSession()
+	assert.NotNil(t, s.CookieJar())
+	// 不能修改全局的DefaultClient
+	assert.Nil(t, DefaultClient.Jar)
+
+	code := 0
+	body := ""
+	assert.NoError(t, s.POST(ts.URL+"/login").Code(&code).Do())
+	assert.Equal(t, 200, code)
+
+	assert.NoError(t, s.GET(ts.URL+"/profile").BindBody(&body).Code(&code).Do())
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "sid-ok", body)
+
+	// 普通的Gout不保存cookie
+	assert.NoError(t, GET(ts.URL+"/profile").Code(&code).Do())
+	assert.Equal(t, 401, code)
+
+	assert.NoError(t, s.POST(ts.URL+"/logout").Do())
+	assert.NoError(t, s.GET(ts.URL+"/profile").Code(&code).Do())
+	assert.Equal(t, 401, code)
+}
+
+func Test_CookieJar_SaveLoad(t *testing.T) {
+	router := setupCookieJar(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "gout-cookie")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, format := range []CookieFormat{CookieJSON, CookieNetscape} {
+		path := filepath.Join(dir, "cookies")
+
+		s := NewSession()
+		assert.NoError(t, s.POST(ts.URL+"/login").Do())
+		assert.NoError(t, s.SaveCookies(path, format))
+
+		s2 := NewSession()
+		assert.NoError(t, s2.LoadCookies(path, format))
+
+		code := 0
+		body := ""
+		assert.NoError(t, s2.GET(ts.URL+"/profile").BindBody(&body).Code(&code).Do())
+		assert.Equal(t, 200, code, "format %d", format)
+		assert.Equal(t, "sid-ok", body, "format %d", format)
+	}
+
+	assert.Equal(t, ErrNoCookieJar, New().SaveCookies(filepath.Join(dir, "x"), CookieJSON))
+	assert.Error(t, NewSession().LoadCookies(filepath.Join(dir, "not-found"), CookieJSON))
+}
+
+func Test_CookieJar_Match(t *testing.T) {
+	jar := NewCookieJar()
+
+	u, _ := url.Parse("http://www.example.com/a/b")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".example.com", Path: "/"},
+		{Name: "secure", Value: "3", Path: "/", Secure: true},
+		{Name: "deep", Value: "4", Path: "/a/b/c"},
+		{Name: "other", Value: "5", Domain: "other.com"},
+		{Name: "expired", Value: "6", Expires: time.Now().Add(-time.Hour)},
+	})
+
+	get := func(rawURL string) (names []string) {
+		u, _ := url.Parse(rawURL)
+		for _, c := range jar.Cookies(u) {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	// 默认path是/a, path长的在前面
+	assert.Equal(t, []string{"host", "domain"}, get("http://www.example.com/a/b"))
+	assert.Equal(t, []string{"host", "domain", "secure"}, get("https://www.example.com/a"))
+	assert.Equal(t, []string{"deep", "host", "domain"}, get("http://www.example.com/a/b/c/d"))
+	assert.Equal(t, []string{"domain"}, get("http://sub.example.com/"))
+	assert.Equal(t, []string{"domain"}, get("http://www.example.com/ab"))
+	assert.Nil(t, get("http://example.org/a"))
+	assert.Len(t, jar.All(), 4)
+}
+
+func Test_CookieJar_Netscape(t *testing.T) {
+	file := "# Netscape HTTP Cookie File\n" +
+		"\n" +
+		".example.com\tTRUE\t/\tFALSE\t0\tsession\ts1\n" +
+		"#HttpOnly_www.example.com\tFALSE\t/\tTRUE\t4102444800\ttoken\tt1\n" +
+		"www.example.com\tFALSE\t/\tFALSE\t946684800\told\to1\n"
+
+	jar := NewCookieJar()
+	assert.NoError(t, jar.Load(bytes.NewBufferString(file), CookieNetscape))
+
+	cookies := jar.All()
+	assert.Len(t, cookies, 2)
+	assert.Equal(t, "session", cookies[0].Name)
+	assert.Equal(t, "example.com", cookies[0].Domain)
+	assert.True(t, cookies[0].Expires.IsZero())
+	assert.Equal(t, "token", cookies[1].Name)
+	assert.True(t, cookies[1].HttpOnly)
+	assert.True(t, cookies[1].Secure)
+	assert.Equal(t, int64(4102444800), cookies[1].Expires.Unix())
+
+	var out bytes.Buffer
+	assert.NoError(t, jar.Save(&out, CookieNetscape))
+	assert.Equal(t, "# Netscape HTTP Cookie File\n\n"+
+		".example.com\tTRUE\t/\tFALSE\t0\tsession\ts1\n"+
+		"#HttpOnly_www.example.com\tFALSE\t/\tTRUE\t4102444800\ttoken\tt1\n", out.String())
+
+	assert.Error(t, jar.Load(bytes.NewBufferString("bad line\n"), CookieNetscape))
+	assert.Error(t, jar.Load(bytes.NewBufferString("{}"), CookieFormat(9)))
+}
+
+// 文件里没有path, domain, name的条目不能让Cookies panic
+func Test_CookieJar_LoadInvalid(t *testing.T) {
+	get := func(jar *CookieJar) (names []string) {
+		u, _ := url.Parse("http://example.com/a/b")
+		for _, c := range jar.Cookies(u) {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	jar := NewCookieJar()
+	err := jar.Load(strings.NewReader(`[
+		{"name":"a","value":"b","domain":"example.com","host_only":true},
+		{"name":"c","value":"d","domain":"example.com","path":"a","host_only":true},
+		{"name":"","value":"e","domain":"example.com","path":"/","host_only":true},
+		{"name":"f","value":"g","domain":"","path":"/","host_only":true}
+	]`), CookieJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, get(jar))
+	for _, c := range jar.All() {
+		assert.Equal(t, "/", c.Path)
+	}
+
+	jar = NewCookieJar()
+	err = jar.Load(strings.NewReader("example.com\tFALSE\t\tFALSE\t0\ta\tb\n"+
+		"example.com\tFALSE\t/\tFALSE\t0\t\te\n"+
+		"\tFALSE\t/\tFALSE\t0\tf\tg\n"), CookieNetscape)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, get(jar))
+}
+
+type testSuffixList map[string]bool
+
+func (l testSuffixList) PublicSuffix(domain string) string {
+	for d := domain; ; {
+		if l[d] {
+			return d
+		}
+
+		i := strings.IndexByte(d, '.')
+		if i == -1 {
+			return d
+		}
+		d = d[i+1:]
+	}
+}
+
+func (l testSuffixList) String() string {
+	return "test"
+}
+
+// 公共后缀不能作为domain
+func Test_CookieJar_PublicSuffix(t *testing.T) {
+	get := func(jar *CookieJar, rawURL string) (names []string) {
+		u, _ := url.Parse(rawURL)
+		for _, c := range jar.Cookies(u) {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	jar := NewCookieJar()
+	u, _ := url.Parse("http://a.example.com/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "super", Value: "1", Domain: "com"},
+		{Name: "domain", Value: "2", Domain: "example.com"},
+	})
+	assert.Nil(t, get(jar, "http://other.com/"))
+	assert.Equal(t, []string{"domain"}, get(jar, "http://b.example.com/"))
+
+	jar = NewCookieJar(testSuffixList{"co.uk": true})
+	u, _ = url.Parse("http://a.example.co.uk/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "super", Value: "1", Domain: "co.uk"},
+		{Name: "domain", Value: "2", Domain: "example.co.uk"},
+	})
+	assert.Nil(t, get(jar, "http://other.co.uk/"))
+	assert.Equal(t, []string{"domain"}, get(jar, "http://b.example.co.uk/"))
+
+	// 公共后缀本身的站点当作host-only
+	u, _ = url.Parse("http://co.uk/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "self", Value: "3", Domain: "co.uk"}})
+	assert.Equal(t, []string{"self"}, get(jar, "http://co.uk/"))
+	assert.Nil(t, get(jar, "http://other.co.uk/"))
+
+	// 文件里的超级cookie不加载
+	jar = NewCookieJar()
+	err := jar.Load(strings.NewReader(".com\tTRUE\t/\tFALSE\t0\tsuper\t1\n"), CookieNetscape)
+	assert.NoError(t, err)
+	assert.Len(t, jar.All(), 0)
+}
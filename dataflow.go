@@ -2,12 +2,9 @@ package gout
 
 import (
 	"context"
-	"fmt"
 	"github.com/guonaihong/gout/decode"
 	"github.com/guonaihong/gout/encode"
-	"net"
 	"net/http"
-	"net/url"
 	"time"
 )
 
@@ -26,39 +23,41 @@ type DataFlow struct {
 	out *Gout
 }
 
-func (df *DataFlow) GET(url string) *DataFlow {
-	df.Req = reqDef(Get, joinPaths("", url), df.out)
+// setMethod 在当前DataFlow上开始一个新请求, 保留之前的debug, SetProxy, UnixSocket, SetTimeout, WithContext设置
+// 比如 New().Debug(true).GET(url), New().UnixSocket(path).GET(url)
+func (df *DataFlow) setMethod(method, url string) *DataFlow {
+	from := df.Req
+	df.Req = reqDef(method, joinPaths("", url), df.out)
+	df.Req.keepOptions(&from)
 	return df
 }
 
+func (df *DataFlow) GET(url string) *DataFlow {
+	return df.setMethod(Get, url)
+}
+
 func (df *DataFlow) POST(url string) *DataFlow {
-	df.Req = reqDef(Post, joinPaths("", url), df.out)
-	return df
+	return df.setMethod(Post, url)
 }
 
 func (df *DataFlow) PUT(url string) *DataFlow {
-	df.Req = reqDef(Put, joinPaths("", url), df.out)
-	return df
+	return df.setMethod(Put, url)
 }
 
 func (df *DataFlow) DELETE(url string) *DataFlow {
-	df.Req = reqDef(Delete, joinPaths("", url), df.out)
-	return df
+	return df.setMethod(Delete, url)
 }
 
 func (df *DataFlow) PATCH(url string) *DataFlow {
-	df.Req = reqDef(Patch, joinPaths("", url), df.out)
-	return df
+	return df.setMethod(Patch, url)
 }
 
 func (df *DataFlow) HEAD(url string) *DataFlow {
-	df.Req = reqDef(Head, joinPaths("", url), df.out)
-	return df
+	return df.setMethod(Head, url)
 }
 
 func (df *DataFlow) OPTIONS(url string) *DataFlow {
-	df.Req = reqDef(Options, joinPaths("", url), df.out)
-	return df
+	return df.setMethod(Options, url)
 }
 
 func (df *DataFlow) SetBody(obj interface{}) *DataFlow {
@@ -81,68 +80,54 @@ func (df *DataFlow) SetQuery(obj interface{}) *DataFlow {
 	return df
 }
 
+// SetPath 替换url里的 :name 和 {name} 占位符
+// obj 可以是带path tag的结构体, map, 或者key/value交替的slice
+func (df *DataFlow) SetPath(obj interface{}) *DataFlow {
+	df.Req.pathEncode = obj
+	return df
+}
+
 func (df *DataFlow) SetHeader(obj interface{}) *DataFlow {
 	df.Req.headerEncode = obj
 	return df
 }
 
 func (df *DataFlow) SetJSON(obj interface{}) *DataFlow {
-	df.out.opt.ReqBodyType = "json"
-	df.Req.bodyEncoder = encode.NewJSONEncode(obj)
+	df.Req.opt.ReqBodyType = "json"
+	df.Req.bodyEncoder = newCodecEncode("json", obj)
 	return df
 }
 
 func (df *DataFlow) SetXML(obj interface{}) *DataFlow {
-	df.out.opt.ReqBodyType = "xml"
-	df.Req.bodyEncoder = encode.NewXMLEncode(obj)
+	df.Req.opt.ReqBodyType = "xml"
+	df.Req.bodyEncoder = newCodecEncode("xml", obj)
 	return df
 }
 
 func (df *DataFlow) SetYAML(obj interface{}) *DataFlow {
-	df.out.opt.ReqBodyType = "yaml"
-	df.Req.bodyEncoder = encode.NewYAMLEncode(obj)
+	df.Req.opt.ReqBodyType = "yaml"
+	df.Req.bodyEncoder = newCodecEncode("yaml", obj)
 	return df
 }
 
-// 此函数会修改Transport
+// UnixSocket 这个DataFlow的请求通过unix domain socket发送, url里的host只用于Host header
+// 不修改Client的Transport, 不影响同一个Client的其他请求
 func (df *DataFlow) UnixSocket(path string) *DataFlow {
-	if df.out.Client.Transport == nil {
-		df.out.Client.Transport = &http.Transport{}
-	}
-
-	transport, ok := df.out.Client.Transport.(*http.Transport)
-	if !ok {
-		df.Req.err = fmt.Errorf("UnixSocket:not found http.transport:%T", df.out.Client.Transport)
-		return df
-	}
-
-	transport.Dial = func(proto, addr string) (conn net.Conn, err error) {
-		return net.Dial("unix", path)
-	}
-
+	df.Req.getRoute().unixSocket = path
 	return df
 }
 
-// 此函数会修改Transport
+// SetProxy 设置这个DataFlow的请求使用的代理
+// 支持http, https, socks5, socks5h, 没有scheme时是http. 用户名密码写在url里
+// 不修改Client的Transport, 不影响同一个Client的其他请求
 func (df *DataFlow) SetProxy(proxyURL string) *DataFlow {
-	proxy, err := url.Parse(modifyURL(proxyURL))
+	proxy, err := parseProxy(proxyURL)
 	if err != nil {
 		df.Req.err = err
 		return df
 	}
 
-	if df.out.Client.Transport == nil {
-		df.out.Client.Transport = &http.Transport{}
-	}
-
-	transport, ok := df.out.Client.Transport.(*http.Transport)
-	if !ok {
-		df.Req.err = fmt.Errorf("SetProxy:not found http.transport:%T", df.out.Client.Transport)
-		return df
-	}
-
-	transport.Proxy = http.ProxyURL(proxy)
-
+	df.Req.getRoute().proxy = proxy
 	return df
 }
 
@@ -162,20 +147,37 @@ func (df *DataFlow) BindHeader(obj interface{}) *DataFlow {
 }
 
 func (df *DataFlow) BindJSON(obj interface{}) *DataFlow {
-	df.out.opt.RspBodyType = "json"
-	df.Req.bodyDecoder = decode.NewJSONDecode(obj)
+	df.Req.opt.RspBodyType = "json"
+	df.Req.bodyDecoder = newCodecDecode("json", obj)
 	return df
 }
 
 func (df *DataFlow) BindXML(obj interface{}) *DataFlow {
-	df.out.opt.RspBodyType = "xml"
-	df.Req.bodyDecoder = decode.NewXMLDecode(obj)
+	df.Req.opt.RspBodyType = "xml"
+	df.Req.bodyDecoder = newCodecDecode("xml", obj)
 	return df
 }
 
 func (df *DataFlow) BindYAML(obj interface{}) *DataFlow {
-	df.out.opt.RspBodyType = "yaml"
-	df.Req.bodyDecoder = decode.NewYAMLDecode(obj)
+	df.Req.opt.RspBodyType = "yaml"
+	df.Req.bodyDecoder = newCodecDecode("yaml", obj)
+	return df
+}
+
+// BindErrorJSON 错误状态码的body解析到obj里, 成功的body照常解析到BindJSON
+// 错误状态码见CheckStatus, 没有调用CheckStatus时是非2xx
+func (df *DataFlow) BindErrorJSON(obj interface{}) *DataFlow {
+	df.Req.errDecoder = newCodecDecode("json", obj)
+	return df
+}
+
+func (df *DataFlow) BindErrorXML(obj interface{}) *DataFlow {
+	df.Req.errDecoder = newCodecDecode("xml", obj)
+	return df
+}
+
+func (df *DataFlow) BindErrorYAML(obj interface{}) *DataFlow {
+	df.Req.errDecoder = newCodecDecode("yaml", obj)
 	return df
 }
 
@@ -207,9 +209,9 @@ func (df *DataFlow) Debug(d ...interface{}) *DataFlow {
 	for _, v := range d {
 		switch opt := v.(type) {
 		case bool:
-			defaultDebug(&df.out.opt)
+			defaultDebug(&df.Req.opt)
 		case DebugOpt:
-			opt.Apply(&df.out.opt)
+			opt.Apply(&df.Req.opt)
 		}
 	}
 
@@ -220,6 +222,10 @@ func (df *DataFlow) Do() (err error) {
 	return df.Req.Do()
 }
 
+func (df *DataFlow) DoResponse() (*Response, error) {
+	return df.Req.DoResponse()
+}
+
 func (df *DataFlow) Filter() *Filter {
 	return &Filter{df: df}
 }
@@ -1196,3 +1196,48 @@ func Test_DataFlow_Timeout(t *testing.T) {
 	assert.Error(t, err)
 	assert.GreaterOrEqual(t, int(time.Now().Sub(s)), int(middleTimeout*time.Millisecond))
 }
+
+func setupPath(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	router.GET("/v1/users/:id/orders/:orderID", func(c *gin.Context) {
+		c.String(200, c.Param("id")+"|"+c.Param("orderID")+"|"+c.Query("q"))
+	})
+
+	return router
+}
+
+func TestSetPath(t *testing.T) {
+	router := setupPath(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	type testPath struct {
+		ID      int    `path:"id"`
+		OrderID string `path:"orderID"`
+	}
+
+	tests := []struct {
+		url  string
+		set  interface{}
+		need string
+	}{
+		{"/v1/users/:id/orders/:orderID", testPath{ID: 1, OrderID: "order 1"}, "1|order 1|q"},
+		{"/v1/users/{id}/orders/{orderID}", H{"id": 2, "orderID": "order?2"}, "2|order?2|q"},
+		{"/v1/users/:id/orders/{orderID}", []string{"id", "3", "orderID", "3"}, "3|3|q"},
+	}
+
+	for _, v := range tests {
+		s := ""
+		err := GET(ts.URL + v.url).SetPath(v.set).SetQuery(H{"q": "q"}).BindBody(&s).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, v.need, s)
+	}
+
+	// 有占位符没有设置值
+	err := GET(ts.URL + "/v1/users/:id/orders/:orderID").SetPath(H{"id": 1}).Do()
+	assert.Error(t, err)
+
+	err = GET(ts.URL + "/v1/users/:id/orders/:orderID").SetPath(1).Do()
+	assert.Error(t, err)
+}
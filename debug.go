@@ -30,6 +30,10 @@ type DebugOption struct {
 	Color       bool
 	ReqBodyType string
 	RspBodyType string
+
+	// SetCodec, BindCodec传入的对象, 用来把二进制body转成json打印
+	reqBodyObj interface{}
+	rspBodyObj interface{}
 }
 
 type DebugOpt interface {
@@ -104,8 +108,21 @@ func (do *DebugOption) debugPrint(req *http.Request, rsp *http.Response) error {
 			return err
 		}
 
+		if p, ok := b.(noProgressBody); ok {
+			b = p.withoutProgress()
+		}
+		defer b.Close()
+
 		var r = io.Reader(b)
-		format := color.NewFormatEncoder(r, do.Color, ToBodyType(do.ReqBodyType))
+		if rc, ok, err := newDecompressReader(req.Header.Get("Content-Encoding"), b); ok && err == nil {
+			defer rc.Close()
+			r = rc
+		}
+
+		var bodyType string
+		r, bodyType = codecToJSON(r, do.ReqBodyType, do.reqBodyObj, do.Color)
+
+		format := color.NewFormatEncoder(r, do.Color, ToBodyType(bodyType))
 		if format != nil {
 			r = format
 		}
@@ -125,7 +142,9 @@ func (do *DebugOption) debugPrint(req *http.Request, rsp *http.Response) error {
 	fmt.Fprintf(w, "\r\n\r\n")
 	// write rsp body
 	var r = io.Reader(rsp.Body)
-	format := color.NewFormatEncoder(r, do.Color, ToBodyType(do.RspBodyType))
+	r, bodyType := codecToJSON(r, do.RspBodyType, do.rspBodyObj, do.Color)
+
+	format := color.NewFormatEncoder(r, do.Color, ToBodyType(bodyType))
 	if format != nil {
 		r = format
 	}
@@ -0,0 +1,26 @@
+package decode
+
+import (
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+type CBORDecode struct {
+	obj interface{}
+}
+
+func NewCBORDecode(obj interface{}) *CBORDecode {
+	if obj == nil {
+		return nil
+	}
+	return &CBORDecode{obj: obj}
+}
+
+func (d *CBORDecode) Decode(r io.Reader) error {
+	return DecodeCBOR(r, d.obj)
+}
+
+func DecodeCBOR(r io.Reader, obj interface{}) error {
+	return cbor.NewDecoder(r).Decode(obj)
+}
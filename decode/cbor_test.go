@@ -0,0 +1,31 @@
+package decode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCBORDecode(t *testing.T) {
+	assert.Nil(t, NewCBORDecode(nil))
+}
+
+func TestCBORDecode_Decode(t *testing.T) {
+	type val struct {
+		A string `cbor:"a"`
+		B int    `cbor:"b"`
+	}
+
+	all, err := cbor.Marshal(val{A: "a", B: 2})
+	assert.NoError(t, err)
+
+	got := val{}
+	assert.NoError(t, NewCBORDecode(&got).Decode(bytes.NewReader(all)))
+	assert.Equal(t, val{A: "a", B: 2}, got)
+
+	got = val{}
+	assert.NoError(t, DecodeCBOR(bytes.NewReader(all), &got))
+	assert.Equal(t, val{A: "a", B: 2}, got)
+}
@@ -0,0 +1,26 @@
+package decode
+
+import (
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type MsgPackDecode struct {
+	obj interface{}
+}
+
+func NewMsgPackDecode(obj interface{}) *MsgPackDecode {
+	if obj == nil {
+		return nil
+	}
+	return &MsgPackDecode{obj: obj}
+}
+
+func (d *MsgPackDecode) Decode(r io.Reader) error {
+	return DecodeMsgPack(r, d.obj)
+}
+
+func DecodeMsgPack(r io.Reader, obj interface{}) error {
+	return msgpack.NewDecoder(r).Decode(obj)
+}
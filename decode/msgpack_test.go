@@ -0,0 +1,31 @@
+package decode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNewMsgPackDecode(t *testing.T) {
+	assert.Nil(t, NewMsgPackDecode(nil))
+}
+
+func TestMsgPackDecode_Decode(t *testing.T) {
+	type val struct {
+		A string `msgpack:"a"`
+		B int    `msgpack:"b"`
+	}
+
+	all, err := msgpack.Marshal(val{A: "a", B: 2})
+	assert.NoError(t, err)
+
+	got := val{}
+	assert.NoError(t, NewMsgPackDecode(&got).Decode(bytes.NewReader(all)))
+	assert.Equal(t, val{A: "a", B: 2}, got)
+
+	got = val{}
+	assert.NoError(t, DecodeMsgPack(bytes.NewReader(all), &got))
+	assert.Equal(t, val{A: "a", B: 2}, got)
+}
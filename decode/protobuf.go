@@ -0,0 +1,39 @@
+package decode
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/guonaihong/gout/core"
+	"google.golang.org/protobuf/proto"
+)
+
+type ProtoBufDecode struct {
+	obj interface{}
+}
+
+func NewProtoBufDecode(obj interface{}) *ProtoBufDecode {
+	if obj == nil {
+		return nil
+	}
+	return &ProtoBufDecode{obj: obj}
+}
+
+func (p *ProtoBufDecode) Decode(r io.Reader) error {
+	return DecodeProtoBuf(r, p.obj)
+}
+
+func DecodeProtoBuf(r io.Reader, obj interface{}) error {
+	msg, ok := obj.(proto.Message)
+	if !ok {
+		return fmt.Errorf("type(%T) %s:", obj, core.ErrUnknownType.Error())
+	}
+
+	all, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(all, msg)
+}
@@ -0,0 +1,30 @@
+package decode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestNewProtoBufDecode(t *testing.T) {
+	assert.Nil(t, NewProtoBufDecode(nil))
+}
+
+func TestProtoBufDecode_Decode(t *testing.T) {
+	all, err := proto.Marshal(wrapperspb.String("test decode protobuf"))
+	assert.NoError(t, err)
+
+	got := &wrapperspb.StringValue{}
+	assert.NoError(t, NewProtoBufDecode(got).Decode(bytes.NewReader(all)))
+	assert.Equal(t, "test decode protobuf", got.GetValue())
+
+	got.Reset()
+	assert.NoError(t, DecodeProtoBuf(bytes.NewReader(all), got))
+	assert.Equal(t, "test decode protobuf", got.GetValue())
+
+	s := ""
+	assert.Error(t, DecodeProtoBuf(bytes.NewReader(all), &s))
+}
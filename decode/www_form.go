@@ -0,0 +1,62 @@
+package decode
+
+import (
+	"io"
+	"io/ioutil"
+	"net/url"
+)
+
+type WWWFormDecode struct {
+	obj interface{}
+}
+
+func NewWWWFormDecode(obj interface{}) *WWWFormDecode {
+	if obj == nil {
+		return nil
+	}
+	return &WWWFormDecode{obj: obj}
+}
+
+func (w *WWWFormDecode) Decode(r io.Reader) error {
+	return DecodeWWWForm(r, w.obj)
+}
+
+// DecodeWWWForm 解析application/x-www-form-urlencoded
+// obj 可以是*url.Values, map[string][]string, map[string]string, 或者带form tag的结构体指针
+func DecodeWWWForm(r io.Reader, obj interface{}) error {
+	all, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(string(all))
+	if err != nil {
+		return err
+	}
+
+	switch o := obj.(type) {
+	case *url.Values:
+		*o = values
+		return nil
+	case *map[string][]string:
+		*o = values
+		return nil
+	case map[string][]string:
+		for k, v := range values {
+			o[k] = v
+		}
+		return nil
+	case url.Values:
+		for k, v := range values {
+			o[k] = v
+		}
+		return nil
+	case map[string]string:
+		for k := range values {
+			o[k] = values.Get(k)
+		}
+		return nil
+	}
+
+	return decode(defaultSet(values), obj, "form")
+}
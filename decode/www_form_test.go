@@ -0,0 +1,37 @@
+package decode
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WWWForm_NewDecode(t *testing.T) {
+	assert.Nil(t, NewWWWFormDecode(nil))
+}
+
+func Test_WWWForm_Decode(t *testing.T) {
+	type formVal struct {
+		A string `form:"a"`
+		B []int  `form:"b"`
+		C bool
+	}
+
+	body := "a=hello&b=1&b=2&C=true"
+
+	got := formVal{}
+	assert.NoError(t, NewWWWFormDecode(&got).Decode(strings.NewReader(body)))
+	assert.Equal(t, formVal{A: "hello", B: []int{1, 2}, C: true}, got)
+
+	values := url.Values{}
+	assert.NoError(t, DecodeWWWForm(strings.NewReader(body), &values))
+	assert.Equal(t, []string{"1", "2"}, values["b"])
+
+	m := map[string]string{}
+	assert.NoError(t, DecodeWWWForm(strings.NewReader(body), m))
+	assert.Equal(t, map[string]string{"a": "hello", "b": "1", "C": "true"}, m)
+
+	assert.Error(t, DecodeWWWForm(strings.NewReader("a=%zz"), &values))
+}
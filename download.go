@@ -0,0 +1,268 @@
+package gout
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 下载中的临时文件后缀, 下载完成之后重命名成目标文件
+const DownloadSuffix = ".download"
+
+var ErrChecksum = errors.New("download:sha256 checksum mismatch")
+
+// Download 把响应body下载到文件
+// 先写入临时文件, 完成并校验之后再原子地重命名成目标文件
+// 临时文件还在时, 下次下载会用Range/If-Range续传
+type Download struct {
+	df       *DataFlow
+	path     string
+	progress func(done, total int64)
+	sum      string
+}
+
+func (df *DataFlow) Download(path string) *Download {
+	return &Download{df: df, path: path}
+}
+
+// Progress 设置进度回调, done是已经写入文件的字节数(包含续传之前的部分)
+// total未知时是-1
+func (d *Download) Progress(cb func(done, total int64)) *Download {
+	d.progress = cb
+	return d
+}
+
+// SHA256 下载完成之后校验文件的sha256(十六进制字符串)
+func (d *Download) SHA256(sum string) *Download {
+	d.sum = strings.ToLower(sum)
+	return d
+}
+
+func (d *Download) tmpPath() string {
+	return d.path + DownloadSuffix
+}
+
+// 保存ETag或者Last-Modified, 续传时作为If-Range的值
+func (d *Download) metaPath() string {
+	return d.tmpPath() + ".meta"
+}
+
+func (d *Download) clean() {
+	os.Remove(d.tmpPath())
+	os.Remove(d.metaPath())
+}
+
+// 强ETag和Last-Modified才可以用于If-Range
+func rangeValidator(h http.Header) string {
+	if etag := h.Get("ETag"); len(etag) > 0 && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return h.Get("Last-Modified")
+}
+
+// 解析 Content-Range: bytes 100-199/200 或者 bytes */200
+func parseContentRange(s string) (start, total int64, err error) {
+	const prefix = "bytes "
+	if !strings.HasPrefix(s, prefix) {
+		return 0, 0, fmt.Errorf("download:invalid Content-Range:%q", s)
+	}
+
+	s = s[len(prefix):]
+	pos := strings.IndexByte(s, '/')
+	if pos == -1 {
+		return 0, 0, fmt.Errorf("download:invalid Content-Range:%q", s)
+	}
+
+	total = -1
+	if s[pos+1:] != "*" {
+		if total, err = strconv.ParseInt(s[pos+1:], 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if s[:pos] == "*" {
+		return 0, total, nil
+	}
+
+	rangeStart := s[:pos]
+	if pos = strings.IndexByte(rangeStart, '-'); pos != -1 {
+		rangeStart = rangeStart[:pos]
+	}
+
+	start, err = strconv.ParseInt(rangeStart, 10, 64)
+	return start, total, err
+}
+
+type progressWriter struct {
+	w     io.Writer
+	done  int64
+	total int64
+	cb    func(done, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (n int, err error) {
+	n, err = p.w.Write(b)
+	p.done += int64(n)
+	if p.cb != nil && n > 0 {
+		p.cb(p.done, p.total)
+	}
+	return n, err
+}
+
+func fileSHA256(path string) (string, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, fd); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (d *Download) Do() (err error) {
+	defer d.df.Req.Reset()
+
+	if d.df.Req.err != nil {
+		return d.df.Req.err
+	}
+
+	req, err := d.df.Req.request()
+	if err != nil {
+		return err
+	}
+
+	fd, err := os.OpenFile(d.tmpPath(), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if fd != nil {
+			fd.Close()
+		}
+	}()
+
+	fi, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+
+	offset := fi.Size()
+	validator := ""
+	if all, err := ioutil.ReadFile(d.metaPath()); err == nil {
+		validator = string(all)
+	}
+
+	// 没有校验值无法确认服务端文件是否变化, 从头下载
+	if offset > 0 && len(validator) > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	} else {
+		offset = 0
+	}
+
+	resp, err := d.df.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			d.clean()
+			return err
+		}
+
+		if start != offset {
+			d.clean()
+			return fmt.Errorf("download:range start %d, want %d", start, offset)
+		}
+		total = size
+
+	case http.StatusOK:
+		offset = 0
+		if resp.ContentLength >= 0 {
+			total = resp.ContentLength
+		}
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 临时文件可能已经是完整的
+		_, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || size != offset {
+			d.clean()
+			return fmt.Errorf("download:unexpected status %s", resp.Status)
+		}
+		total = size
+		resp.Body = http.NoBody
+
+	default:
+		d.clean()
+		return fmt.Errorf("download:unexpected status %s", resp.Status)
+	}
+
+	if err = fd.Truncate(offset); err != nil {
+		return err
+	}
+
+	if _, err = fd.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	validator = rangeValidator(resp.Header)
+	if err = ioutil.WriteFile(d.metaPath(), []byte(validator), 0644); err != nil {
+		return err
+	}
+
+	pw := &progressWriter{w: fd, done: offset, total: total, cb: d.progress}
+	if _, err = io.Copy(pw, resp.Body); err != nil {
+		// 保留临时文件, 下次续传; 不能续传时删除
+		if len(validator) == 0 {
+			d.clean()
+		}
+		return err
+	}
+
+	if err = fd.Sync(); err != nil {
+		return err
+	}
+
+	err = fd.Close()
+	fd = nil
+	if err != nil {
+		return err
+	}
+
+	if len(d.sum) > 0 {
+		sum, err := fileSHA256(d.tmpPath())
+		if err != nil {
+			return err
+		}
+
+		if sum != d.sum {
+			d.clean()
+			return ErrChecksum
+		}
+	}
+
+	if err = os.Rename(d.tmpPath(), d.path); err != nil {
+		return err
+	}
+
+	os.Remove(d.metaPath())
+	return nil
+}
@@ -0,0 +1,206 @@
+package gout
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDownloadServer struct {
+	data      []byte
+	etag      string
+	lastRange atomic.Value
+	// 大于0时, 只发送这么多字节就断开连接
+	breakAt int32
+}
+
+func (s *testDownloadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lastRange.Store(r.Header.Get("Range"))
+	w.Header().Set("ETag", s.etag)
+
+	if n := atomic.LoadInt32(&s.breakAt); n > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		w.Write(s.data[:n])
+		panic(http.ErrAbortHandler)
+	}
+
+	http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(s.data))
+}
+
+func newTestDownload(t *testing.T) (*testDownloadServer, string, func()) {
+	data := bytes.Repeat([]byte("0123456789"), 10*1024)
+	dir, err := ioutil.TempDir("", "gout-download")
+	assert.NoError(t, err)
+
+	return &testDownloadServer{data: data, etag: `"v1"`}, dir, func() { os.RemoveAll(dir) }
+}
+
+func testSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func Test_Download_Do(t *testing.T) {
+	s, dir, cleanup := newTestDownload(t)
+	defer cleanup()
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	path := filepath.Join(dir, "data.bin")
+	var lastDone, lastTotal int64
+	err := GET(ts.URL).
+		Download(path).
+		Progress(func(done, total int64) {
+			assert.True(t, done > lastDone)
+			lastDone, lastTotal = done, total
+		}).
+		SHA256(testSHA256(s.data)).
+		Do()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(s.data)), lastDone)
+	assert.Equal(t, int64(len(s.data)), lastTotal)
+
+	all, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, s.data, all)
+
+	_, err = os.Stat(path + DownloadSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_Download_Resume(t *testing.T) {
+	s, dir, cleanup := newTestDownload(t)
+	defer cleanup()
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	path := filepath.Join(dir, "data.bin")
+
+	// 第一次下载到一半断开, 临时文件保留
+	atomic.StoreInt32(&s.breakAt, 4096)
+	err := GET(ts.URL).Download(path).Do()
+	assert.Error(t, err)
+
+	fi, err := os.Stat(path + DownloadSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4096), fi.Size())
+
+	// 续传
+	atomic.StoreInt32(&s.breakAt, 0)
+	var firstDone int64 = -1
+	err = GET(ts.URL).
+		Download(path).
+		Progress(func(done, total int64) {
+			if firstDone == -1 {
+				firstDone = done
+			}
+		}).
+		SHA256(testSHA256(s.data)).
+		Do()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "bytes=4096-", s.lastRange.Load())
+	assert.True(t, firstDone > 4096)
+
+	all, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, s.data, all)
+}
+
+func Test_Download_Changed(t *testing.T) {
+	s, dir, cleanup := newTestDownload(t)
+	defer cleanup()
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	path := filepath.Join(dir, "data.bin")
+
+	// 临时文件来自旧版本, If-Range不匹配, 服务端返回完整内容
+	assert.NoError(t, ioutil.WriteFile(path+DownloadSuffix, []byte("old data"), 0644))
+	assert.NoError(t, ioutil.WriteFile(path+DownloadSuffix+".meta", []byte(`"v0"`), 0644))
+
+	err := GET(ts.URL).Download(path).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "bytes=8-", s.lastRange.Load())
+
+	all, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, s.data, all)
+
+	// 临时文件已经完整, 服务端返回416
+	assert.NoError(t, ioutil.WriteFile(path+DownloadSuffix, s.data, 0644))
+	assert.NoError(t, ioutil.WriteFile(path+DownloadSuffix+".meta", []byte(s.etag), 0644))
+	err = GET(ts.URL).Download(path).SHA256(testSHA256(s.data)).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "bytes="+strconv.Itoa(len(s.data))+"-", s.lastRange.Load())
+
+	// 没有校验值, 不续传
+	assert.NoError(t, ioutil.WriteFile(path+DownloadSuffix, []byte("old data"), 0644))
+	err = GET(ts.URL).Download(path).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "", s.lastRange.Load())
+}
+
+func Test_Download_Fail(t *testing.T) {
+	s, dir, cleanup := newTestDownload(t)
+	defer cleanup()
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	path := filepath.Join(dir, "data.bin")
+
+	// 校验失败, 删除临时文件
+	err := GET(ts.URL).Download(path).SHA256("00").Do()
+	assert.Equal(t, ErrChecksum, err)
+
+	for _, p := range []string{path, path + DownloadSuffix, path + DownloadSuffix + ".meta"} {
+		_, err = os.Stat(p)
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+
+	err = GET(notFound.URL).Download(path).Do()
+	assert.Error(t, err)
+
+	err = GET(ts.URL).SetQuery(1).Download(path).Do()
+	assert.Error(t, err)
+}
+
+func Test_Download_parseContentRange(t *testing.T) {
+	start, total, err := parseContentRange("bytes 100-199/200")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), start)
+	assert.Equal(t, int64(200), total)
+
+	start, total, err = parseContentRange("bytes */200")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), start)
+	assert.Equal(t, int64(200), total)
+
+	_, total, err = parseContentRange("bytes 100-199/*")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), total)
+
+	for _, s := range []string{"", "bytes 100-199", "items 1-2/3", "bytes a-b/3", "bytes 1-2/x"} {
+		_, _, err = parseContentRange(s)
+		assert.Error(t, err)
+	}
+}
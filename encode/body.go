@@ -19,6 +19,12 @@ func NewBodyEncode(obj interface{}) *BodyEncode {
 	return &BodyEncode{obj: obj}
 }
 
+// Reader obj是io.Reader时直接返回, 调用方可以不经过内存缓冲流式发送
+func (b *BodyEncode) Reader() (io.Reader, bool) {
+	r, ok := b.obj.(io.Reader)
+	return r, ok
+}
+
 func (b *BodyEncode) Encode(w io.Writer) error {
 	if r, ok := b.obj.(io.Reader); ok {
 		_, err := io.Copy(w, r)
@@ -0,0 +1,27 @@
+package encode
+
+import (
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+type CBOREncode struct {
+	obj interface{}
+}
+
+func NewCBOREncode(obj interface{}) *CBOREncode {
+	if obj == nil {
+		return nil
+	}
+
+	return &CBOREncode{obj: obj}
+}
+
+func (e *CBOREncode) Encode(w io.Writer) error {
+	return cbor.NewEncoder(w).Encode(e.obj)
+}
+
+func (e *CBOREncode) Name() string {
+	return "cbor"
+}
@@ -0,0 +1,37 @@
+package encode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCBOR struct {
+	I int     `cbor:"i"`
+	F float64 `cbor:"f"`
+	S string  `cbor:"s"`
+}
+
+func TestNewCBOREncode(t *testing.T) {
+	assert.Nil(t, NewCBOREncode(nil))
+}
+
+func TestCBOREncode_Name(t *testing.T) {
+	assert.Equal(t, "cbor", NewCBOREncode("").Name())
+}
+
+func TestCBOREncode_Encode(t *testing.T) {
+	need := testCBOR{I: 100, F: 3.14, S: "test encode cbor"}
+
+	out := bytes.Buffer{}
+	for _, v := range []interface{}{need, &need} {
+		out.Reset()
+		assert.NoError(t, NewCBOREncode(v).Encode(&out))
+
+		got := testCBOR{}
+		assert.NoError(t, cbor.Unmarshal(out.Bytes(), &got))
+		assert.Equal(t, need, got)
+	}
+}
@@ -1,12 +1,11 @@
 package encode
 
 import (
-	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"mime/multipart"
 	"net/textproto"
+	"os"
 	"reflect"
 	"strconv"
 	"strings"
@@ -18,10 +17,60 @@ var _ Adder = (*FormEncode)(nil)
 
 type FormEncode struct {
 	*multipart.Writer
+
+	// 只用于计算长度, 文件不读取内容, 只累加文件大小
+	sizeOnly bool
+	fileSize int64
+	// 有管道, 设备这类不是普通文件的part, 长度未知
+	unknownSize bool
+
+	// PartWriter 可以包装每个part的writer, 比如统计上传进度
+	// size是part内容的长度, 未知时是-1
+	PartWriter func(fieldName, fileName string, size int64, w io.Writer) io.Writer
+}
+
+func (f *FormEncode) wrapPart(w io.Writer, fieldName, fileName string, size int64) io.Writer {
+	if f.PartWriter == nil {
+		return w
+	}
+
+	return f.PartWriter(fieldName, fileName, size, w)
 }
 
-func NewFormEncode(b *bytes.Buffer) *FormEncode {
-	return &FormEncode{Writer: multipart.NewWriter(b)}
+func NewFormEncode(w io.Writer) *FormEncode {
+	return &FormEncode{Writer: multipart.NewWriter(w)}
+}
+
+type countWriter int64
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	*c += countWriter(len(p))
+	return len(p), nil
+}
+
+// FormSize 计算obj编码成multipart之后的长度, 文件只统计大小, 不读取内容
+// boundary 需要和真正编码时用的一致. 有文件不是普通文件(管道, /dev/stdin)时返回-1
+func FormSize(obj interface{}, boundary string) (int64, error) {
+	var c countWriter
+	f := NewFormEncode(&c)
+	f.sizeOnly = true
+	if err := f.SetBoundary(boundary); err != nil {
+		return 0, err
+	}
+
+	if err := Encode(obj, f); err != nil {
+		return 0, err
+	}
+
+	if err := f.End(); err != nil {
+		return 0, err
+	}
+
+	if f.unknownSize {
+		return -1, nil
+	}
+
+	return int64(c) + f.fileSize, nil
 }
 
 func toBytes(val reflect.Value) (all []byte, err error) {
@@ -74,9 +123,7 @@ func (f *FormEncode) formFileWrite(key string, v reflect.Value, openFile bool) (
 			return fmt.Errorf("unknown type formFileWrite:%T, openFile:%t", v, openFile)
 		}
 
-		if all, err = ioutil.ReadFile(fileName); err != nil {
-			return err
-		}
+		return f.fileWrite(key, fileRealName, contentType, fileName)
 	} else {
 		switch val := v.Interface().(type) {
 		case core.FormType:
@@ -104,7 +151,44 @@ func (f *FormEncode) formFileWrite(key string, v reflect.Value, openFile bool) (
 		return err
 	}
 
-	_, err = part.Write(all)
+	_, err = f.wrapPart(part, key, fileRealName, int64(len(all))).Write(all)
+	return err
+}
+
+// fileWrite 打开文件, 边读边写到part里, 不会把整个文件读到内存
+func (f *FormEncode) fileWrite(key, fileRealName, contentType, fileName string) error {
+	if f.sizeOnly {
+		fi, err := os.Stat(fileName)
+		if err != nil {
+			return err
+		}
+
+		if !fi.Mode().IsRegular() {
+			f.unknownSize = true
+		}
+
+		f.fileSize += fi.Size()
+		_, err = f.CreateFormFile(key, fileRealName, contentType)
+		return err
+	}
+
+	fd, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	size := int64(-1)
+	if fi, err := fd.Stat(); err == nil && fi.Mode().IsRegular() {
+		size = fi.Size()
+	}
+
+	part, err := f.CreateFormFile(key, fileRealName, contentType)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f.wrapPart(part, key, fileRealName, size), fd)
 	return err
 }
 
@@ -121,10 +205,7 @@ func (f *FormEncode) mapFormFile(key string, v reflect.Value, sf reflect.StructF
 
 		switch ft := val.File.(type) {
 		case core.FormFile:
-			all, err = ioutil.ReadFile(string(ft))
-			if err != nil {
-				return false, err
-			}
+			return false, f.fileWrite(key, fileName, contentType, string(ft))
 
 		case core.FormMem:
 			all = []byte(ft)
@@ -133,10 +214,7 @@ func (f *FormEncode) mapFormFile(key string, v reflect.Value, sf reflect.StructF
 		}
 
 	case core.FormFile:
-		all, err = ioutil.ReadFile(string(val))
-		if err != nil {
-			return false, err
-		}
+		return false, f.fileWrite(key, fileName, contentType, string(val))
 
 	case core.FormMem:
 		all = []byte(val)
@@ -149,7 +227,7 @@ func (f *FormEncode) mapFormFile(key string, v reflect.Value, sf reflect.StructF
 		return false, err
 	}
 
-	_, err = part.Write(all)
+	_, err = f.wrapPart(part, key, fileName, int64(len(all))).Write(all)
 	return false, err
 }
 
@@ -223,7 +301,7 @@ func (f *FormEncode) formFieldWrite(key string, v reflect.Value) error {
 		return err
 	}
 
-	_, err = part.Write(all)
+	_, err = f.wrapPart(part, key, "", int64(len(all))).Write(all)
 	return err
 }
 
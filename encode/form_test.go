@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"mime/multipart"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -346,3 +347,69 @@ func Test_Form_Name(t *testing.T) {
 	f := NewFormEncode(&bytes.Buffer{})
 	assert.Equal(t, f.Name(), "form")
 }
+
+func Test_Form_FormSize(t *testing.T) {
+	tests := []interface{}{
+		core.H{
+			"mode":  "A",
+			"text":  "good morning",
+			"voice": core.FormFile("../testdata/voice.pcm"),
+			"mem":   core.FormMem("pcm pcm"),
+		},
+		core.H{
+			"voice": core.FormType{FileName: "voice.pcm", File: core.FormFile("../testdata/voice.pcm")},
+		},
+		test_Form_struct{
+			Mode:   "A",
+			Voice:  "pcm data",
+			Voice2: "../testdata/voice.pcm",
+		},
+	}
+
+	for _, v := range tests {
+		var out bytes.Buffer
+		f := NewFormEncode(&out)
+		assert.NoError(t, Encode(v, f))
+		assert.NoError(t, f.End())
+
+		size, err := FormSize(v, f.Boundary())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(out.Len()), size)
+	}
+
+	_, err := FormSize(core.H{"voice": core.FormFile("not found")}, "boundary")
+	assert.Error(t, err)
+
+	_, err = FormSize(core.H{"mode": "A"}, "")
+	assert.Error(t, err)
+
+	// 设备文件的大小不可信, 长度未知
+	if fi, err := os.Stat(os.DevNull); err == nil && !fi.Mode().IsRegular() {
+		size, err := FormSize(core.H{"mode": "A", "dev": core.FormFile(os.DevNull)}, "boundary")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-1), size)
+	}
+}
+
+func Test_Form_PartWriter(t *testing.T) {
+	var out bytes.Buffer
+	f := NewFormEncode(&out)
+
+	sizes := map[string]int64{}
+	f.PartWriter = func(fieldName, fileName string, size int64, w io.Writer) io.Writer {
+		sizes[fieldName] = size
+		return w
+	}
+
+	err := Encode(core.H{
+		"mode":  "A",
+		"voice": core.FormFile("../testdata/voice.pcm"),
+		"mem":   core.FormMem("pcm pcm"),
+	}, f)
+	assert.NoError(t, err)
+	assert.NoError(t, f.End())
+
+	all, err := ioutil.ReadFile("../testdata/voice.pcm")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"mode": 1, "voice": int64(len(all)), "mem": 7}, sizes)
+}
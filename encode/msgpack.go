@@ -0,0 +1,27 @@
+package encode
+
+import (
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type MsgPackEncode struct {
+	obj interface{}
+}
+
+func NewMsgPackEncode(obj interface{}) *MsgPackEncode {
+	if obj == nil {
+		return nil
+	}
+
+	return &MsgPackEncode{obj: obj}
+}
+
+func (e *MsgPackEncode) Encode(w io.Writer) error {
+	return msgpack.NewEncoder(w).Encode(e.obj)
+}
+
+func (e *MsgPackEncode) Name() string {
+	return "msgpack"
+}
@@ -0,0 +1,37 @@
+package encode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type testMsgPack struct {
+	I int     `msgpack:"i"`
+	F float64 `msgpack:"f"`
+	S string  `msgpack:"s"`
+}
+
+func TestNewMsgPackEncode(t *testing.T) {
+	assert.Nil(t, NewMsgPackEncode(nil))
+}
+
+func TestMsgPackEncode_Name(t *testing.T) {
+	assert.Equal(t, "msgpack", NewMsgPackEncode("").Name())
+}
+
+func TestMsgPackEncode_Encode(t *testing.T) {
+	need := testMsgPack{I: 100, F: 3.14, S: "test encode msgpack"}
+
+	out := bytes.Buffer{}
+	for _, v := range []interface{}{need, &need} {
+		out.Reset()
+		assert.NoError(t, NewMsgPackEncode(v).Encode(&out))
+
+		got := testMsgPack{}
+		assert.NoError(t, msgpack.Unmarshal(out.Bytes(), &got))
+		assert.Equal(t, need, got)
+	}
+}
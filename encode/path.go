@@ -0,0 +1,98 @@
+package encode
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+var _ Adder = (*PathEncode)(nil)
+
+type PathEncode struct {
+	values map[string]string
+}
+
+func NewPathEncode() *PathEncode {
+	return &PathEncode{values: make(map[string]string)}
+}
+
+func (p *PathEncode) Add(key string, v reflect.Value, sf reflect.StructField) error {
+	p.values[key] = valToStr(v, sf)
+	return nil
+}
+
+func (p *PathEncode) Name() string {
+	return "path"
+}
+
+func (p *PathEncode) value(name string) (string, error) {
+	v, ok := p.values[name]
+	if !ok {
+		return "", fmt.Errorf("path:placeholder %q has no value", name)
+	}
+
+	return url.PathEscape(v), nil
+}
+
+// 替换一段path里的{name}
+func (p *PathEncode) replaceBrace(seg string) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.IndexByte(seg, '{')
+		if start == -1 {
+			break
+		}
+
+		end := strings.IndexByte(seg[start:], '}')
+		if end == -1 {
+			break
+		}
+
+		v, err := p.value(seg[start+1 : start+end])
+		if err != nil {
+			return "", err
+		}
+
+		out.WriteString(seg[:start])
+		out.WriteString(v)
+		seg = seg[start+end+1:]
+	}
+
+	out.WriteString(seg)
+	return out.String(), nil
+}
+
+// Replace 替换url path里的 :name 和 {name} 占位符, 值会被转义
+// 有占位符没有对应的值时返回错误
+func (p *PathEncode) Replace(rawURL string) (string, error) {
+	prefix := ""
+	if pos := strings.Index(rawURL, "://"); pos != -1 {
+		hostEnd := strings.IndexByte(rawURL[pos+3:], '/')
+		if hostEnd == -1 {
+			return rawURL, nil
+		}
+		prefix, rawURL = rawURL[:pos+3+hostEnd], rawURL[pos+3+hostEnd:]
+	}
+
+	suffix := ""
+	if pos := strings.IndexAny(rawURL, "?#"); pos != -1 {
+		rawURL, suffix = rawURL[:pos], rawURL[pos:]
+	}
+
+	segs := strings.Split(rawURL, "/")
+	for i, seg := range segs {
+		var err error
+		if strings.HasPrefix(seg, ":") {
+			segs[i], err = p.value(seg[1:])
+		} else {
+			segs[i], err = p.replaceBrace(seg)
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return prefix + strings.Join(segs, "/") + suffix, nil
+}
@@ -0,0 +1,50 @@
+package encode
+
+import (
+	"testing"
+
+	"github.com/guonaihong/gout/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPath struct {
+	ID      int    `path:"id"`
+	OrderID string `path:"orderID"`
+}
+
+func Test_Path_Replace(t *testing.T) {
+	tests := []struct {
+		set  interface{}
+		url  string
+		need string
+	}{
+		{testPath{ID: 1, OrderID: "a/b"}, "http://127.0.0.1:8080/v1/users/:id/orders/:orderID", "http://127.0.0.1:8080/v1/users/1/orders/a%2Fb"},
+		{testPath{ID: 1, OrderID: "a b"}, "http://127.0.0.1/v1/users/{id}/orders/{orderID}.json?q=1", "http://127.0.0.1/v1/users/1/orders/a%20b.json?q=1"},
+		{core.H{"id": 2}, "/v1/users/:id", "/v1/users/2"},
+		{[]string{"id", "3", "name", "x"}, "/v1/users/{id}-{name}", "/v1/users/3-x"},
+		{core.H{"id": 2}, "http://127.0.0.1:8080", "http://127.0.0.1:8080"},
+	}
+
+	for _, v := range tests {
+		p := NewPathEncode()
+		assert.NoError(t, Encode(v.set, p))
+
+		got, err := p.Replace(v.url)
+		assert.NoError(t, err)
+		assert.Equal(t, v.need, got)
+	}
+}
+
+func Test_Path_Fail(t *testing.T) {
+	p := NewPathEncode()
+	assert.NoError(t, Encode(core.H{"id": 1}, p))
+
+	for _, u := range []string{"/v1/users/:id/orders/:orderID", "/v1/users/{id}/orders/{orderID}"} {
+		_, err := p.Replace(u)
+		assert.Error(t, err)
+	}
+}
+
+func Test_Path_Name(t *testing.T) {
+	assert.Equal(t, "path", NewPathEncode().Name())
+}
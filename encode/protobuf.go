@@ -0,0 +1,40 @@
+package encode
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/guonaihong/gout/core"
+	"google.golang.org/protobuf/proto"
+)
+
+type ProtoBufEncode struct {
+	obj interface{}
+}
+
+func NewProtoBufEncode(obj interface{}) *ProtoBufEncode {
+	if obj == nil {
+		return nil
+	}
+
+	return &ProtoBufEncode{obj: obj}
+}
+
+func (p *ProtoBufEncode) Encode(w io.Writer) error {
+	msg, ok := p.obj.(proto.Message)
+	if !ok {
+		return fmt.Errorf("type(%T) %s:", p.obj, core.ErrUnknownType.Error())
+	}
+
+	all, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(all)
+	return err
+}
+
+func (p *ProtoBufEncode) Name() string {
+	return "protobuf"
+}
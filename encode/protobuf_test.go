@@ -0,0 +1,30 @@
+package encode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestNewProtoBufEncode(t *testing.T) {
+	assert.Nil(t, NewProtoBufEncode(nil))
+}
+
+func TestProtoBufEncode_Name(t *testing.T) {
+	assert.Equal(t, "protobuf", NewProtoBufEncode("").Name())
+}
+
+func TestProtoBufEncode_Encode(t *testing.T) {
+	msg := wrapperspb.String("test encode protobuf")
+	need, err := proto.Marshal(msg)
+	assert.NoError(t, err)
+
+	out := bytes.Buffer{}
+	assert.NoError(t, NewProtoBufEncode(msg).Encode(&out))
+	assert.Equal(t, need, out.Bytes())
+
+	assert.Error(t, NewProtoBufEncode("not a message").Encode(&out))
+}
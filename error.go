@@ -0,0 +1,97 @@
+package gout
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// HTTPError 保留的body最大长度
+var MaxErrorBodySize = 4 * 1024
+
+// HTTPError 打开CheckStatus之后, 错误的状态码会返回*HTTPError
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	// 最多保留MaxErrorBodySize个字节
+	Body []byte
+	// BindErrorJSON等设置的对象解析失败时的错误, 比如网关返回的html页面
+	DecodeErr error
+}
+
+func (e *HTTPError) Error() string {
+	if e.DecodeErr != nil {
+		return fmt.Sprintf("gout:unexpected http status %s, decode error body: %v", e.Status, e.DecodeErr)
+	}
+	return fmt.Sprintf("gout:unexpected http status %s", e.Status)
+}
+
+// Unwrap 返回DecodeErr, 可以用errors.Is, errors.As判断
+func (e *HTTPError) Unwrap() error {
+	return e.DecodeErr
+}
+
+// CheckStatus 打开状态码检查, Do()遇到错误的状态码时返回*HTTPError
+// 没有参数时非2xx都是错误, 有参数时只有这些状态码是错误
+func (df *DataFlow) CheckStatus(codes ...int) *DataFlow {
+	df.Req.checkStatus = true
+	df.Req.errorCodes = codes
+	return df
+}
+
+func (r *Req) isErrorStatus(code int) bool {
+	if !r.checkStatus && r.errDecoder == nil {
+		return false
+	}
+
+	if len(r.errorCodes) == 0 {
+		return code < 200 || code > 299
+	}
+
+	for _, c := range r.errorCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bindError 错误的body解析到BindErrorJSON等设置的对象里, 不会再解析到BindJSON里
+// 没有设置错误对象时最多只读MaxErrorBodySize个字节
+// 打开CheckStatus时错误对象解析失败也返回*HTTPError, 解析的错误放在DecodeErr里
+func (r *Req) bindError(resp *http.Response) error {
+	var body io.Reader = resp.Body
+	if r.errDecoder == nil {
+		body = io.LimitReader(resp.Body, int64(MaxErrorBodySize))
+	}
+
+	all, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	var decodeErr error
+	if r.errDecoder != nil {
+		decodeErr = r.errDecoder.Decode(bytes.NewReader(all))
+	}
+
+	if !r.checkStatus {
+		return decodeErr
+	}
+
+	if len(all) > MaxErrorBodySize {
+		all = all[:MaxErrorBodySize]
+	}
+
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       all,
+		DecodeErr:  decodeErr,
+	}
+}
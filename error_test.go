@@ -0,0 +1,181 @@
+package gout
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type testAPIError struct {
+	ErrMsg  string `json:"errmsg" xml:"errmsg"`
+	ErrCode int    `json:"errcode" xml:"errcode"`
+}
+
+func setupError(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	router.GET("/200", func(c *gin.Context) {
+		c.JSON(200, data{Id: 1, Data: "ok"})
+	})
+
+	router.GET("/404", func(c *gin.Context) {
+		c.Header("sid", "sid-404")
+		c.JSON(404, testAPIError{ErrMsg: "not found", ErrCode: 404})
+	})
+
+	router.GET("/500.xml", func(c *gin.Context) {
+		c.XML(500, testAPIError{ErrMsg: "fail", ErrCode: 500})
+	})
+
+	router.GET("/502.html", func(c *gin.Context) {
+		c.Data(502, "text/html", []byte("<html><body>502 Bad Gateway</body></html>"))
+	})
+
+	router.GET("/big", func(c *gin.Context) {
+		c.String(500, strings.Repeat("x", MaxErrorBodySize*2))
+	})
+
+	return router
+}
+
+func Test_Error_CheckStatus(t *testing.T) {
+	router := setupError(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	// 成功的body解析到BindJSON
+	var ok data
+	var apiErr testAPIError
+	err := GET(ts.URL + "/200").CheckStatus().BindJSON(&ok).BindErrorJSON(&apiErr).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, data{Id: 1, Data: "ok"}, ok)
+	assert.Equal(t, testAPIError{}, apiErr)
+
+	// 错误的body解析到BindErrorJSON
+	ok = data{}
+	code := 0
+	err = GET(ts.URL + "/404").CheckStatus().BindJSON(&ok).BindErrorJSON(&apiErr).Code(&code).Do()
+	assert.Error(t, err)
+	assert.Equal(t, 404, code)
+	assert.Equal(t, data{}, ok)
+	assert.Equal(t, testAPIError{ErrMsg: "not found", ErrCode: 404}, apiErr)
+
+	var httpErr *HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, 404, httpErr.StatusCode)
+	assert.Equal(t, "sid-404", httpErr.Header.Get("sid"))
+	assert.Contains(t, string(httpErr.Body), "not found")
+	assert.Contains(t, httpErr.Error(), "404")
+
+	apiErr = testAPIError{}
+	err = GET(ts.URL + "/500.xml").CheckStatus().BindErrorXML(&apiErr).Do()
+	assert.Error(t, err)
+	assert.Equal(t, testAPIError{ErrMsg: "fail", ErrCode: 500}, apiErr)
+
+	// body只保留MaxErrorBodySize
+	err = GET(ts.URL + "/big").CheckStatus().Do()
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Len(t, httpErr.Body, MaxErrorBodySize)
+}
+
+type countReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// 没有错误对象时不会把整个body读到内存里
+func Test_Error_BodyLimit(t *testing.T) {
+	body := &countReader{r: strings.NewReader(strings.Repeat("x", MaxErrorBodySize*10))}
+	resp := &http.Response{StatusCode: 500, Status: "500 Internal Server Error", Body: ioutil.NopCloser(body)}
+
+	r := &Req{checkStatus: true}
+	err := r.bindError(resp)
+
+	var httpErr *HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Len(t, httpErr.Body, MaxErrorBodySize)
+	assert.Equal(t, MaxErrorBodySize, body.n)
+}
+
+func Test_Error_Codes(t *testing.T) {
+	router := setupError(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	// 只有500是错误
+	s := ""
+	err := GET(ts.URL + "/404").CheckStatus(500).BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Contains(t, s, "not found")
+
+	err = GET(ts.URL + "/500.xml").CheckStatus(500).Do()
+	assert.Error(t, err)
+
+	// 没有打开CheckStatus, 只是分开解析
+	var ok data
+	var apiErr testAPIError
+	err = GET(ts.URL + "/404").BindJSON(&ok).BindErrorJSON(&apiErr).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, data{}, ok)
+	assert.Equal(t, 404, apiErr.ErrCode)
+
+	// 错误的body解析失败
+	err = GET(ts.URL + "/500.xml").BindErrorJSON(&apiErr).Do()
+	assert.Error(t, err)
+	var httpErr *HTTPError
+	assert.False(t, errors.As(err, &httpErr))
+
+	// 默认行为不变
+	code := 0
+	err = GET(ts.URL + "/404").Code(&code).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, 404, code)
+}
+
+// 错误的body不是json时(比如网关的html页面)仍然返回*HTTPError
+func Test_Error_DecodeFail(t *testing.T) {
+	router := setupError(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	var apiErr testAPIError
+	code := 0
+	err := GET(ts.URL + "/502.html").CheckStatus().BindErrorJSON(&apiErr).Code(&code).Do()
+	assert.Error(t, err)
+	assert.Equal(t, 502, code)
+
+	var httpErr *HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, 502, httpErr.StatusCode)
+	assert.Contains(t, string(httpErr.Body), "502 Bad Gateway")
+	assert.Error(t, httpErr.DecodeErr)
+	assert.True(t, errors.Is(err, httpErr.DecodeErr))
+	assert.Contains(t, err.Error(), "decode error body")
+}
+
+func Test_Error_DoResponse(t *testing.T) {
+	router := setupError(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	rsp, err := GET(ts.URL + "/404").CheckStatus().DoResponse()
+	assert.Error(t, err)
+	assert.Equal(t, 404, rsp.StatusCode)
+
+	var apiErr testAPIError
+	assert.NoError(t, rsp.BindJSON(&apiErr))
+	assert.Equal(t, 404, apiErr.ErrCode)
+}
@@ -1,10 +1,18 @@
 module github.com/guonaihong/gout
 
+// errors.Is, errors.As和fmt.Errorf的%w需要go 1.13
 go 1.13
 
 require (
+	github.com/andybalholm/brotli v1.0.4
+	github.com/fxamacker/cbor/v2 v2.5.0
 	github.com/gin-gonic/gin v1.4.1-0.20190924141841-9b9f4fab34cc
+	github.com/gorilla/websocket v1.5.0
+	github.com/klauspost/compress v1.11.13
 	github.com/mattn/go-isatty v0.0.9
-	github.com/stretchr/testify v1.4.0
+	github.com/stretchr/testify v1.6.1
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110
+	google.golang.org/protobuf v1.30.0
 	gopkg.in/yaml.v2 v2.2.2
 )
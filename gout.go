@@ -4,11 +4,33 @@ import (
 	"net/http"
 )
 
+// Gout 可以被多个goroutine同时使用
+// GET, POST等方法每次都会返回一个独立的*DataFlow
+// SetBaseURL, Use这类设置默认值的方法需要在并发使用之前调用
 type Gout struct {
 	*http.Client
 	DataFlow
 
-	opt DebugOption
+	// 客户端级别的默认值, 每个请求都会合并进去
+	baseURL     string
+	defHeader   interface{}
+	defQuery    interface{}
+	defCookies  []*http.Cookie
+	defAuth     *auth
+	defSigner   Signer
+	defCompress *compressOpt
+
+	middlewares []Middleware
+
+	// digest认证缓存的质询
+	digest digestCache
+
+	oauth2 *OAuth2
+
+	transport transportOpt
+
+	// SetProxy, UnixSocket使用的Transport
+	routes *routeCache
 }
 
 var (
@@ -28,6 +50,11 @@ func New(c ...*http.Client) *Gout {
 		out.Client = c[0]
 	}
 
+	out.routes = newRouteCache()
+	if out.Client == &DefaultClient {
+		out.routes = defaultRouteCache
+	}
+
 	out.DataFlow.out = out
 	return out
 }
@@ -37,6 +64,67 @@ func Def() *Gout {
 	return New()
 }
 
+// SetBaseURL 设置基础url, 非http://, https://, ws://, wss://开头的url会拼接在它后面
+func (g *Gout) SetBaseURL(baseURL string) *Gout {
+	g.baseURL = baseURL
+	return g
+}
+
+// SetDefaultHeader 设置默认http header, 请求里的SetHeader会覆盖同名的key
+func (g *Gout) SetDefaultHeader(obj interface{}) *Gout {
+	g.defHeader = obj
+	return g
+}
+
+// SetDefaultQuery 设置默认query, 请求里的SetQuery会覆盖同名的key
+func (g *Gout) SetDefaultQuery(obj interface{}) *Gout {
+	g.defQuery = obj
+	return g
+}
+
+// SetDefaultCookies 设置默认cookie, 请求里的SetCookies会覆盖同名的cookie
+func (g *Gout) SetDefaultCookies(c ...*http.Cookie) *Gout {
+	g.defCookies = append(g.defCookies, c...)
+	return g
+}
+
+// newDataFlow 返回独立的*DataFlow, 不和其他请求共享状态
+// 继承g上的Debug, SetProxy, UnixSocket, SetTimeout, WithContext设置, 比如 g.Debug(true); g.GET(url)
+func (g *Gout) newDataFlow(method, url string) *DataFlow {
+	df := &DataFlow{out: g}
+	df.Req = reqDef(method, joinPaths("", url), g)
+	df.Req.keepOptions(&g.DataFlow.Req)
+	return df
+}
+
+func (g *Gout) GET(url string) *DataFlow {
+	return g.newDataFlow(Get, url)
+}
+
+func (g *Gout) POST(url string) *DataFlow {
+	return g.newDataFlow(Post, url)
+}
+
+func (g *Gout) PUT(url string) *DataFlow {
+	return g.newDataFlow(Put, url)
+}
+
+func (g *Gout) DELETE(url string) *DataFlow {
+	return g.newDataFlow(Delete, url)
+}
+
+func (g *Gout) PATCH(url string) *DataFlow {
+	return g.newDataFlow(Patch, url)
+}
+
+func (g *Gout) HEAD(url string) *DataFlow {
+	return g.newDataFlow(Head, url)
+}
+
+func (g *Gout) OPTIONS(url string) *DataFlow {
+	return g.newDataFlow(Options, url)
+}
+
 func GET(url string) *DataFlow {
 	return New().GET(url)
 }
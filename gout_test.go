@@ -1,12 +1,15 @@
 package gout
 
 import (
+	"bytes"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -72,3 +75,186 @@ func TestTopMethod(t *testing.T) {
 
 	assert.Equal(t, int(total), 7)
 }
+
+func setupDefault(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	router.GET("/api/v1/default", func(c *gin.Context) {
+		cookie, err := c.Request.Cookie("session")
+		assert.NoError(t, err)
+
+		c.JSON(200, H{
+			"token":   c.GetHeader("token"),
+			"trace":   c.GetHeader("trace"),
+			"tenant":  c.Query("tenant"),
+			"page":    c.Query("page"),
+			"session": cookie.Value,
+		})
+	})
+
+	return router
+}
+
+func TestGoutDefault(t *testing.T) {
+	router := setupDefault(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	g := New().
+		SetBaseURL(ts.URL + "/api").
+		SetDefaultHeader(H{"token": "def-token", "trace": "def-trace"}).
+		SetDefaultQuery(H{"tenant": "t1", "page": 1}).
+		SetDefaultCookies(&http.Cookie{Name: "session", Value: "def-session"})
+
+	type rspBody struct {
+		Token   string `json:"token"`
+		Trace   string `json:"trace"`
+		Tenant  string `json:"tenant"`
+		Page    string `json:"page"`
+		Session string `json:"session"`
+	}
+
+	// 只使用默认值
+	var got rspBody
+	err := g.GET("/v1/default").BindJSON(&got).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, rspBody{Token: "def-token", Trace: "def-trace", Tenant: "t1", Page: "1", Session: "def-session"}, got)
+
+	// 请求里的值覆盖默认值
+	got = rspBody{}
+	err = g.GET("v1/default").
+		SetHeader(H{"token": "req-token"}).
+		SetQuery("page=2").
+		SetCookies(&http.Cookie{Name: "session", Value: "req-session"}).
+		BindJSON(&got).
+		Do()
+	assert.NoError(t, err)
+	assert.Equal(t, rspBody{Token: "req-token", Trace: "def-trace", Tenant: "t1", Page: "2", Session: "req-session"}, got)
+
+	// 绝对地址不拼接baseURL
+	got = rspBody{}
+	err = g.GET(ts.URL + "/api/v1/default").BindJSON(&got).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "def-token", got.Token)
+}
+
+func TestGoutDefaultFail(t *testing.T) {
+	err := New().SetDefaultQuery(1).GET("127.0.0.1").Do()
+	assert.Error(t, err)
+
+	err = New().SetDefaultHeader(1).GET("127.0.0.1").Do()
+	assert.Error(t, err)
+}
+
+func setupParallel(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	router.POST("/parallel", func(c *gin.Context) {
+		var d data
+		err := c.BindJSON(&d)
+		assert.NoError(t, err)
+
+		c.Header("sid", c.GetHeader("sid"))
+		d.Data = c.Query("q")
+		c.JSON(200, d)
+	})
+
+	return router
+}
+
+// 多个goroutine共用一个*Gout, 每个请求互不影响
+// go test -race -run Test_Gout_Parallel
+func Test_Gout_Parallel(t *testing.T) {
+	router := setupParallel(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	g := New().SetBaseURL(ts.URL).SetDefaultHeader(H{"def": "def"})
+
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			i := i
+			t.Run(fmt.Sprintf("goroutine-%d", i), func(t *testing.T) {
+				t.Parallel()
+
+				for j := 0; j < 20; j++ {
+					id := i*100 + j
+					sid := fmt.Sprint(id)
+
+					var buf bytes.Buffer
+					var got data
+					var header struct {
+						Sid string `header:"sid"`
+					}
+					code := 0
+
+					err := g.POST("/parallel").
+						Debug(DebugFunc(func(o *DebugOption) {
+							o.Debug = true
+							o.Write = &buf
+						})).
+						SetJSON(data{Id: id}).
+						SetHeader(H{"sid": sid}).
+						SetQuery(H{"q": sid}).
+						BindJSON(&got).
+						BindHeader(&header).
+						Code(&code).
+						Do()
+
+					assert.NoError(t, err)
+					assert.Equal(t, 200, code)
+					assert.Equal(t, data{Id: id, Data: sid}, got)
+					assert.Equal(t, sid, header.Sid)
+					assert.Contains(t, buf.String(), fmt.Sprintf(`"id":%d`, id))
+				}
+			})
+		}
+	})
+}
+
+func Test_Gout_Independent(t *testing.T) {
+	g := New()
+	df1 := g.GET("/1").Debug(true)
+	df2 := g.POST("/2")
+
+	assert.NotEqual(t, fmt.Sprintf("%p", df1), fmt.Sprintf("%p", df2))
+	assert.True(t, df1.Req.opt.Debug)
+	assert.False(t, df2.Req.opt.Debug)
+	assert.Equal(t, "http://127.0.0.1/1", df1.Req.url)
+	assert.Equal(t, "http://127.0.0.1/2", df2.Req.url)
+
+	// 在同一个DataFlow上开始新请求, 保留debug选项
+	df := New().Debug(true).GET("/3")
+	assert.True(t, df.Req.opt.Debug)
+}
+
+// 在Gout上设置的debug, SetTimeout, UnixSocket, SetProxy会带到GET, POST等新请求里
+func Test_Gout_InheritOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	g := New()
+	g.Debug(DebugFunc(func(o *DebugOption) {
+		o.Debug = true
+		o.Write = &buf
+	}))
+	g.SetTimeout(time.Second)
+	g.UnixSocket("/tmp/gout.sock")
+
+	df := g.GET(ts.URL)
+	assert.True(t, df.Req.opt.Debug)
+	assert.Equal(t, time.Second, df.Req.timeout)
+	assert.Equal(t, "/tmp/gout.sock", df.Req.route.unixSocket)
+
+	// 请求上的修改不影响Gout
+	df.SetProxy("http://127.0.0.1:1")
+	assert.Nil(t, g.DataFlow.Req.route.proxy)
+
+	g.DataFlow.Req.route = nil
+	err := g.GET(ts.URL).Do()
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "GET / HTTP/1.1")
+}
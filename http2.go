@@ -0,0 +1,390 @@
+package gout
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// HTTP2Mode 使用HTTP/2的方式
+type HTTP2Mode int
+
+const (
+	// HTTP2Only 只使用TLS上的HTTP/2, 服务端的ALPN不支持h2时返回错误
+	HTTP2Only HTTP2Mode = iota + 1
+	// H2CPriorKnowledge http://直接发送HTTP/2连接前言(RFC 7540 3.4)
+	H2CPriorKnowledge
+	// H2CUpgrade http://先用HTTP/1.1的Upgrade: h2c协商(RFC 7540 3.2)
+	// 服务端同意之后这个host后面的请求直接使用h2c, 不同意就一直使用HTTP/1.1
+	H2CUpgrade
+)
+
+var ErrHTTP2NotSupported = errors.New("gout:server does not support http2")
+
+// http2.Transport的DialTLS拿不到请求的context, 没有设置TLSHandshakeTimeout时拨号加握手最多等这么久
+const defaultHTTP2DialTimeout = 10 * time.Second
+
+// SetHTTP2 设置HTTP/2的使用方式, h2c模式下https://的请求还是走原来的Transport
+// 会复制一份Client, 不修改传入的Client和DefaultClient
+// 继承原来*http.Transport的TLS和拨号(UnixSocket)设置, HTTP/2和h2c不使用代理
+func (g *Gout) SetHTTP2(mode HTTP2Mode) *Gout {
+	g.updateTransport(func(o *transportOpt) {
+		o.http2Mode = mode
+	})
+	return g
+}
+
+// newHTTP2Transport 使用base的TLS和拨号设置, h2c模式下其他请求交给h1
+func newHTTP2Transport(mode HTTP2Mode, base *http.Transport, h1 http.RoundTripper) http.RoundTripper {
+	dial := transportDialer(base)
+
+	timeout := base.TLSHandshakeTimeout
+	if timeout <= 0 {
+		timeout = defaultHTTP2DialTimeout
+	}
+
+	switch mode {
+	case HTTP2Only:
+		return &http2.Transport{
+			TLSClientConfig: base.TLSClientConfig,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+
+				conn, err := dial(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+
+				deadline, _ := ctx.Deadline()
+				tc := tls.Client(conn, cfg)
+				tc.SetDeadline(deadline)
+				if err = tc.Handshake(); err != nil {
+					conn.Close()
+					return nil, err
+				}
+				tc.SetDeadline(time.Time{})
+
+				if tc.ConnectionState().NegotiatedProtocol != http2.NextProtoTLS {
+					conn.Close()
+					return nil, ErrHTTP2NotSupported
+				}
+				return tc, nil
+			},
+		}
+	case H2CPriorKnowledge, H2CUpgrade:
+		h2c := &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+				return dial(ctx, network, addr)
+			},
+		}
+
+		if mode == H2CPriorKnowledge {
+			return &h2cTransport{h1: h1, h2c: h2c}
+		}
+		return &h2cUpgradeTransport{h1: h1, h2c: h2c, dial: dial}
+	}
+
+	return h1
+}
+
+// h2cTransport http://使用h2c, https://使用原来的Transport
+type h2cTransport struct {
+	h1  http.RoundTripper
+	h2c http.RoundTripper
+}
+
+func (t *h2cTransport) CloseIdleConnections() {
+	closeIdleConnections(t.h1)
+	closeIdleConnections(t.h2c)
+}
+
+func (t *h2cTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" {
+		return t.h1.RoundTrip(req)
+	}
+	return t.h2c.RoundTrip(req)
+}
+
+// h2cUpgradeTransport 每个host第一个没有body的请求带上Upgrade: h2c
+// 服务端返回101之后, 这个请求的响应在HTTP/2的stream 1上读取, 读完关闭连接, 后面的请求直接使用h2c
+type h2cUpgradeTransport struct {
+	h1   http.RoundTripper
+	h2c  http.RoundTripper
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// host -> bool, 是否支持h2c
+	hosts sync.Map
+}
+
+// SETTINGS_ENABLE_PUSH=0, base64url编码
+const h2cUpgradeSettings = "AAIAAAAA"
+
+func (t *h2cUpgradeTransport) CloseIdleConnections() {
+	closeIdleConnections(t.h1)
+	closeIdleConnections(t.h2c)
+}
+
+func (t *h2cUpgradeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" {
+		return t.h1.RoundTrip(req)
+	}
+
+	if ok, known := t.hosts.Load(req.URL.Host); known {
+		if ok.(bool) {
+			return t.h2c.RoundTrip(req)
+		}
+		return t.h1.RoundTrip(req)
+	}
+
+	// 带body的请求用HTTP/1.1发送, 不做Upgrade
+	if req.Body != nil && req.Body != http.NoBody {
+		return t.h1.RoundTrip(req)
+	}
+
+	return t.upgrade(req)
+}
+
+func (t *h2cUpgradeTransport) upgrade(req *http.Request) (*http.Response, error) {
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		addr = net.JoinHostPort(req.URL.Hostname(), "80")
+	}
+
+	ctx := req.Context()
+	c, err := t.dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// 连接关闭之前context结束(包括读body的时候)时关闭连接
+	conn := &onceCloseConn{Conn: c, closed: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-conn.closed:
+		}
+	}()
+
+	r := req.Clone(ctx)
+	r.Header.Set("Connection", "Upgrade, HTTP2-Settings")
+	r.Header.Set("Upgrade", "h2c")
+	r.Header.Set("HTTP2-Settings", h2cUpgradeSettings)
+
+	resp, err := t.writeUpgrade(r, conn)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+func (t *h2cUpgradeTransport) writeUpgrade(req *http.Request, conn net.Conn) (*http.Response, error) {
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		// 服务端不支持, 这就是HTTP/1.1的响应
+		t.hosts.Store(req.URL.Host, false)
+		resp.Body = &connBody{ReadCloser: resp.Body, conn: conn}
+		return resp, nil
+	}
+
+	resp.Body.Close()
+	t.hosts.Store(req.URL.Host, true)
+	return readH2CStream1(conn, br)
+}
+
+// readH2CStream1 发送连接前言, 读取stream 1的响应头, body在goroutine里边读边返回
+func readH2CStream1(conn net.Conn, br *bufio.Reader) (*http.Response, error) {
+	fr := http2.NewFramer(conn, br)
+	fr.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+
+	if _, err := io.WriteString(conn, http2.ClientPreface); err != nil {
+		return nil, err
+	}
+
+	if err := fr.WriteSettings(http2.Setting{ID: http2.SettingEnablePush, Val: 0}); err != nil {
+		return nil, err
+	}
+
+	for {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		if err = h2cControlFrame(fr, f); err != nil {
+			return nil, err
+		}
+
+		h, ok := f.(*http2.MetaHeadersFrame)
+		if !ok || h.StreamID != 1 {
+			continue
+		}
+
+		resp, err := h2cResponse(h)
+		if err != nil {
+			return nil, err
+		}
+
+		// 1xx的中间响应
+		if resp.StatusCode < 200 {
+			continue
+		}
+
+		if h.StreamEnded() {
+			fr.WriteGoAway(0, http2.ErrCodeNo, nil)
+			conn.Close()
+			resp.Body = http.NoBody
+			return resp, nil
+		}
+
+		pr, pw := io.Pipe()
+		resp.Body = &connBody{ReadCloser: pr, conn: conn}
+		go readH2CBody(fr, conn, pw)
+		return resp, nil
+	}
+}
+
+// h2cControlFrame 回复SETTINGS, PING, stream 1被重置或者连接被关闭时返回错误
+func h2cControlFrame(fr *http2.Framer, f http2.Frame) error {
+	switch f := f.(type) {
+	case *http2.SettingsFrame:
+		if !f.IsAck() {
+			return fr.WriteSettingsAck()
+		}
+	case *http2.PingFrame:
+		if !f.IsAck() {
+			return fr.WritePing(true, f.Data)
+		}
+	case *http2.RSTStreamFrame:
+		if f.StreamID == 1 {
+			return http2.StreamError{StreamID: 1, Code: f.ErrCode}
+		}
+	case *http2.GoAwayFrame:
+		return http2.GoAwayError{LastStreamID: f.LastStreamID, ErrCode: f.ErrCode}
+	}
+
+	return nil
+}
+
+func h2cResponse(h *http2.MetaHeadersFrame) (*http.Response, error) {
+	status := h.PseudoValue("status")
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return nil, fmt.Errorf("gout:h2c malformed status %q", status)
+	}
+
+	resp := &http.Response{
+		Status:        status + " " + http.StatusText(code),
+		StatusCode:    code,
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		Header:        make(http.Header),
+		ContentLength: -1,
+	}
+
+	for _, hf := range h.RegularFields() {
+		resp.Header.Add(http.CanonicalHeaderKey(hf.Name), hf.Value)
+	}
+
+	if cl, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		resp.ContentLength = cl
+	}
+
+	return resp, nil
+}
+
+// readH2CBody 把stream 1的DATA写到管道里, 读走之后再发送WINDOW_UPDATE
+func readH2CBody(fr *http2.Framer, conn net.Conn, pw *io.PipeWriter) {
+	defer conn.Close()
+
+	for {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		if err = h2cControlFrame(fr, f); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		if f.Header().StreamID != 1 {
+			continue
+		}
+
+		if d, ok := f.(*http2.DataFrame); ok && d.Header().Length > 0 {
+			if _, err = pw.Write(d.Data()); err != nil {
+				return
+			}
+
+			// 流量控制包括padding
+			n := d.Header().Length
+			fr.WriteWindowUpdate(0, n)
+			fr.WriteWindowUpdate(1, n)
+		}
+
+		// DATA或者trailer
+		if f.Header().Flags.Has(http2.FlagDataEndStream) {
+			fr.WriteGoAway(0, http2.ErrCodeNo, nil)
+			pw.Close()
+			return
+		}
+	}
+}
+
+// connBody 关闭body的时候同时关闭连接
+type connBody struct {
+	io.ReadCloser
+	conn net.Conn
+}
+
+func (b *connBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.conn.Close()
+	return err
+}
+
+// onceCloseConn 可以重复Close, 关闭之后closed被关闭
+type onceCloseConn struct {
+	net.Conn
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (c *onceCloseConn) Close() (err error) {
+	c.once.Do(func() {
+		close(c.closed)
+		err = c.Conn.Close()
+	})
+	return err
+}
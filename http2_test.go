@@ -0,0 +1,117 @@
+package gout
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func testHTTP2Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/big" {
+			w.Write([]byte(strings.Repeat("a", 200*1024)))
+			return
+		}
+		w.Write([]byte(r.Proto))
+	})
+}
+
+func Test_HTTP2_Only(t *testing.T) {
+	ts := httptest.NewUnstartedServer(testHTTP2Handler())
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	var s string
+	err := New(ts.Client()).SetHTTP2(HTTP2Only).GET(ts.URL).BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", s)
+
+	// 服务端不支持h2
+	ts1 := httptest.NewTLSServer(testHTTP2Handler())
+	defer ts1.Close()
+
+	err = New(ts1.Client()).SetHTTP2(HTTP2Only).GET(ts1.URL).Do()
+	assert.Error(t, err)
+}
+
+// 服务端不回复TLS握手时按TLSHandshakeTimeout返回错误
+func Test_HTTP2_HandshakeTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	c := &http.Client{Transport: &http.Transport{TLSHandshakeTimeout: 100 * time.Millisecond}}
+	s := time.Now()
+	err = New(c).SetHTTP2(HTTP2Only).GET("https://" + l.Addr().String()).Do()
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(s)), int64(5*time.Second))
+}
+
+func Test_HTTP2_H2C(t *testing.T) {
+	ts := httptest.NewServer(h2c.NewHandler(testHTTP2Handler(), &http2.Server{}))
+	defer ts.Close()
+
+	for _, mode := range []HTTP2Mode{H2CPriorKnowledge, H2CUpgrade} {
+		// 第一次是Upgrade, 后面是prior knowledge
+		g := New(&http.Client{}).SetHTTP2(mode)
+		for i := 0; i < 2; i++ {
+			var s string
+			err := g.GET(ts.URL).BindBody(&s).Do()
+			assert.NoError(t, err)
+			assert.Equal(t, "HTTP/2.0", s, "mode %d #%d", mode, i)
+		}
+
+		var s string
+		err := g.POST(ts.URL).SetBody("body").BindBody(&s).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, "HTTP/2.0", s)
+	}
+
+	// Upgrade的响应体比初始窗口大
+	var s string
+	err := New(&http.Client{}).SetHTTP2(H2CUpgrade).GET(ts.URL + "/big").BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, 200*1024, len(s))
+}
+
+// 服务端不支持h2c时使用HTTP/1.1
+func Test_HTTP2_H2CUpgradeFallback(t *testing.T) {
+	ts := httptest.NewServer(testHTTP2Handler())
+	defer ts.Close()
+
+	g := New(&http.Client{}).SetHTTP2(H2CUpgrade)
+	for i := 0; i < 2; i++ {
+		var s string
+		err := g.GET(ts.URL).BindBody(&s).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, "HTTP/1.1", s)
+	}
+}
+
+// 不修改传入的Client
+func Test_HTTP2_NotModifyClient(t *testing.T) {
+	c := &http.Client{}
+	New(c).SetHTTP2(H2CPriorKnowledge)
+	assert.Nil(t, c.Transport)
+
+	New().SetHTTP2(H2CPriorKnowledge)
+	assert.Nil(t, DefaultClient.Transport)
+}
@@ -0,0 +1,41 @@
+package gout
+
+import (
+	"errors"
+	"net/http"
+)
+
+var ErrNilResponse = errors.New("gout:middleware returned nil response")
+
+// HandlerFunc 发送请求, 返回响应. 最内层是http.Client.Do
+type HandlerFunc func(*http.Request) (*http.Response, error)
+
+// Middleware 包装下一个HandlerFunc
+// 可以在调用next之前修改*http.Request, 之后查看或者替换*http.Response
+// 不调用next直接返回, 就是短路这次请求
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Use 注册中间件, 先注册的在最外层
+func (g *Gout) Use(m ...Middleware) *Gout {
+	g.middlewares = append(g.middlewares, m...)
+	return g
+}
+
+func (g *Gout) do(req *http.Request) (*http.Response, error) {
+	c, err := g.client(req)
+	if err != nil {
+		return nil, err
+	}
+
+	h := HandlerFunc(c.Do)
+	for i := len(g.middlewares) - 1; i >= 0; i-- {
+		h = g.middlewares[i](h)
+	}
+
+	resp, err := h(req)
+	if err == nil && resp == nil {
+		return nil, ErrNilResponse
+	}
+
+	return resp, err
+}
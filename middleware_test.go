@@ -0,0 +1,139 @@
+package gout
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupMiddleware(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	router.GET("/middleware", func(c *gin.Context) {
+		c.Header("rsp-sign", c.GetHeader("sign"))
+		c.String(200, "server")
+	})
+
+	return router
+}
+
+func Test_Middleware_Order(t *testing.T) {
+	router := setupMiddleware(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+".before")
+				resp, err := next(req)
+				order = append(order, name+".after")
+				return resp, err
+			}
+		}
+	}
+
+	var elapsed time.Duration
+	timing := func(next HandlerFunc) HandlerFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			elapsed = time.Since(start)
+			return resp, err
+		}
+	}
+
+	sign := func(next HandlerFunc) HandlerFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("sign", "sign-value")
+			return next(req)
+		}
+	}
+
+	s := ""
+	header := struct {
+		Sign string `header:"rsp-sign"`
+	}{}
+
+	err := New().
+		Use(trace("a"), trace("b")).
+		Use(timing, sign).
+		GET(ts.URL + "/middleware").
+		BindBody(&s).
+		BindHeader(&header).
+		Do()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "server", s)
+	assert.Equal(t, "sign-value", header.Sign)
+	assert.Equal(t, []string{"a.before", "b.before", "b.after", "a.after"}, order)
+	assert.NotEqual(t, time.Duration(0), elapsed)
+}
+
+func Test_Middleware_ShortCircuit(t *testing.T) {
+	mock := func(next HandlerFunc) HandlerFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 201,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(bytes.NewBufferString("mock")),
+				Request:    req,
+			}, nil
+		}
+	}
+
+	code := 0
+	s := ""
+	// 没有服务监听这个端口, 请求不会真正发出
+	err := New().Use(mock).GET(retry_doesNotExist).BindBody(&s).Code(&code).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, 201, code)
+	assert.Equal(t, "mock", s)
+
+	// 返回nil响应
+	nilRsp := func(next HandlerFunc) HandlerFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			return nil, nil
+		}
+	}
+
+	err = New().Use(nilRsp).GET(retry_doesNotExist).Do()
+	assert.Equal(t, ErrNilResponse, err)
+}
+
+func Test_Middleware_ReplaceResponse(t *testing.T) {
+	router := setupMiddleware(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	replace := func(next HandlerFunc) HandlerFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := next(req)
+			if err != nil {
+				return nil, err
+			}
+
+			resp.Body.Close()
+			resp.Body = ioutil.NopCloser(bytes.NewBufferString("replace"))
+			return resp, nil
+		}
+	}
+
+	s := ""
+	err := New().Use(replace).GET(ts.URL + "/middleware").BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "replace", s)
+
+	// retry也会经过中间件
+	s = ""
+	err = New().Use(replace).GET(ts.URL + "/middleware").BindBody(&s).Filter().Retry().Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "replace", s)
+}
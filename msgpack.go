@@ -0,0 +1,44 @@
+package gout
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/guonaihong/gout/decode"
+	"github.com/guonaihong/gout/encode"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type msgPackCodec struct{}
+
+func (msgPackCodec) Encode(w io.Writer, obj interface{}) error {
+	return encode.NewMsgPackEncode(obj).Encode(w)
+}
+
+func (msgPackCodec) Decode(r io.Reader, obj interface{}) error {
+	return decode.DecodeMsgPack(r, obj)
+}
+
+// DebugJSON map的key是字符串时才能转成json
+func (msgPackCodec) DebugJSON(body []byte, obj interface{}) ([]byte, error) {
+	var v interface{}
+	if err := msgpack.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+func init() {
+	RegisterCodec("msgpack", "application/msgpack", msgPackCodec{})
+}
+
+// SetMsgPack 发送MessagePack编码的body, Content-Type是application/msgpack
+func (df *DataFlow) SetMsgPack(obj interface{}) *DataFlow {
+	return df.SetCodec("msgpack", obj)
+}
+
+// BindMsgPack 解析MessagePack编码的body
+func (df *DataFlow) BindMsgPack(obj interface{}) *DataFlow {
+	return df.BindCodec("msgpack", obj)
+}
@@ -0,0 +1,80 @@
+package gout
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type testBinaryCodec struct {
+	Name  string   `msgpack:"name" cbor:"name"`
+	Stars int      `msgpack:"stars" cbor:"stars"`
+	Tags  []string `msgpack:"tags" cbor:"tags"`
+}
+
+// 原样返回body和Content-Type
+func setupBinaryEcho(t *testing.T) *httptest.Server {
+	router := gin.New()
+	router.POST("/echo", func(c *gin.Context) {
+		all, err := c.GetRawData()
+		assert.NoError(t, err)
+		c.Data(200, c.GetHeader("Content-Type"), all)
+	})
+
+	return httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+}
+
+func testBinaryCodecDo(t *testing.T, set func(*DataFlow, interface{}) *DataFlow,
+	bind func(*DataFlow, interface{}) *DataFlow, contentType string) {
+
+	ts := setupBinaryEcho(t)
+	defer ts.Close()
+
+	need := testBinaryCodec{Name: "gout", Stars: 100, Tags: []string{"http", "go"}}
+
+	for _, color := range []bool{false, true} {
+		var buf bytes.Buffer
+		var header struct {
+			ContentType string `header:"content-type"`
+		}
+		got := testBinaryCodec{}
+
+		df := POST(ts.URL + "/echo")
+		err := bind(set(df, need), &got).
+			BindHeader(&header).
+			Debug(DebugFunc(func(o *DebugOption) {
+				o.Debug = true
+				o.Color = color
+				o.Write = &buf
+			})).
+			Do()
+
+		assert.NoError(t, err)
+		assert.Equal(t, need, got)
+		assert.Equal(t, contentType, header.ContentType)
+
+		// debug打印的是json
+		if color {
+			assert.Contains(t, buf.String(), "gout")
+			assert.NotContains(t, buf.String(), "\xa4gout")
+		} else {
+			assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte(`"name":"gout"`)), buf.String())
+		}
+	}
+
+	// BindAuto根据Content-Type选择
+	got := testBinaryCodec{}
+	assert.NoError(t, set(POST(ts.URL+"/echo"), need).BindAuto(&got).Do())
+	assert.Equal(t, need, got)
+}
+
+func Test_MsgPack(t *testing.T) {
+	testBinaryCodecDo(t,
+		func(df *DataFlow, obj interface{}) *DataFlow { return df.SetMsgPack(obj) },
+		func(df *DataFlow, obj interface{}) *DataFlow { return df.BindMsgPack(obj) },
+		"application/msgpack")
+}
@@ -0,0 +1,203 @@
+package gout
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrOAuth2Token = errors.New("gout:oauth2 token endpoint returned no access_token")
+
+// 默认提前多久刷新token
+var DefaultExpiryDelta = 10 * time.Second
+
+// OAuth2Config token endpoint的配置
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// 不为空时使用refresh_token grant, 否则使用client_credentials grant
+	RefreshToken string
+
+	// 过期之前多久刷新, 为0时使用DefaultExpiryDelta
+	ExpiryDelta time.Duration
+
+	// 请求token endpoint使用的client, 为nil时使用DefaultClient
+	Client *http.Client
+}
+
+// Token token endpoint返回的token
+type Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	// 零值表示服务端没有返回expires_in, 一直用到收到401
+	Expiry time.Time
+}
+
+// valid 距离过期还有delta以上
+func (t *Token) valid(delta time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+
+	return t.Expiry.IsZero() || time.Now().Add(delta).Before(t.Expiry)
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// OAuth2 缓存token, 快过期时自动刷新, 可以被多个goroutine同时使用
+type OAuth2 struct {
+	cfg OAuth2Config
+
+	mu           sync.Mutex
+	token        *Token
+	refreshToken string
+}
+
+func NewOAuth2(cfg OAuth2Config) *OAuth2 {
+	if cfg.ExpiryDelta == 0 {
+		cfg.ExpiryDelta = DefaultExpiryDelta
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = &DefaultClient
+	}
+
+	return &OAuth2{cfg: cfg, refreshToken: cfg.RefreshToken}
+}
+
+// SetOAuth2 没有设置其他认证的请求都带上o的Bearer token
+// 收到401时刷新token再重发一次
+func (g *Gout) SetOAuth2(o *OAuth2) *Gout {
+	g.oauth2 = o
+	return g
+}
+
+// Token 返回缓存的token, 快过期时先刷新
+func (o *OAuth2) Token() (*Token, error) {
+	return o.getToken(context.Background(), nil)
+}
+
+// getToken 同一时间只有一个goroutine请求token endpoint, 其他的等待结果
+// old不为nil时表示old已经被服务端拒绝, 如果缓存的还是old就重新获取
+func (o *OAuth2) getToken(ctx context.Context, old *Token) (*Token, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token != old && o.token.valid(o.cfg.ExpiryDelta) {
+		return o.token, nil
+	}
+
+	t, err := o.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	o.token = t
+	if t.RefreshToken != "" {
+		o.refreshToken = t.RefreshToken
+	}
+
+	return t, nil
+}
+
+func (o *OAuth2) fetch(ctx context.Context) (*Token, error) {
+	form := H{}
+	if o.refreshToken != "" {
+		form["grant_type"] = "refresh_token"
+		form["refresh_token"] = o.refreshToken
+	} else {
+		form["grant_type"] = "client_credentials"
+	}
+
+	if len(o.cfg.Scopes) > 0 {
+		form["scope"] = strings.Join(o.cfg.Scopes, " ")
+	}
+
+	var rsp tokenResponse
+	err := New(o.cfg.Client).POST(o.cfg.TokenURL).
+		WithContext(ctx).
+		SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(o.cfg.ClientSecret)).
+		SetWWWForm(form).
+		BindJSON(&rsp).
+		CheckStatus().
+		Do()
+	if err != nil {
+		return nil, err
+	}
+
+	if rsp.AccessToken == "" {
+		return nil, ErrOAuth2Token
+	}
+
+	t := &Token{
+		AccessToken:  rsp.AccessToken,
+		TokenType:    rsp.TokenType,
+		RefreshToken: rsp.RefreshToken,
+	}
+
+	if rsp.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(rsp.ExpiresIn) * time.Second)
+	}
+
+	return t, nil
+}
+
+func (t *Token) authorize(req *http.Request) {
+	typ := t.TokenType
+	if typ == "" || strings.EqualFold(typ, "bearer") {
+		typ = "Bearer"
+	}
+
+	req.Header.Set("Authorization", typ+" "+t.AccessToken)
+}
+
+// do 带上token发送请求, 收到401时换一个token重发一次
+func (o *OAuth2) do(g *Gout, req *http.Request) (*http.Response, error) {
+	t, err := o.getToken(req.Context(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	t.authorize(req)
+	resp, err := g.do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// 不能重放body, 把401交给调用者
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	if t, err = o.getToken(req.Context(), t); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if req.GetBody != nil {
+		if req.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	t.authorize(req)
+	return g.do(req)
+}
@@ -0,0 +1,217 @@
+package gout
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOAuth2Server struct {
+	issued    int32
+	expiresIn int
+	grants    []string
+	mu        sync.Mutex
+
+	// 服务端认为有效的token
+	valid string
+}
+
+func (s *testOAuth2Server) setup(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	router.POST("/token", func(c *gin.Context) {
+		id, secret, ok := c.Request.BasicAuth()
+		if !ok || id != "client" || secret != "secret" {
+			c.JSON(401, H{"error": "invalid_client"})
+			return
+		}
+
+		grant := c.PostForm("grant_type")
+		if grant == "refresh_token" && c.PostForm("refresh_token") == "" {
+			c.JSON(400, H{"error": "invalid_grant"})
+			return
+		}
+
+		n := atomic.AddInt32(&s.issued, 1)
+		token := fmt.Sprintf("token-%d", n)
+
+		s.mu.Lock()
+		s.grants = append(s.grants, grant+":"+c.PostForm("refresh_token")+":"+c.PostForm("scope"))
+		s.valid = token
+		s.mu.Unlock()
+
+		c.JSON(200, H{
+			"access_token":  token,
+			"token_type":    "bearer",
+			"expires_in":    s.expiresIn,
+			"refresh_token": fmt.Sprintf("refresh-%d", n),
+		})
+	})
+
+	router.POST("/api", func(c *gin.Context) {
+		s.mu.Lock()
+		valid := "Bearer " + s.valid
+		s.mu.Unlock()
+
+		if c.GetHeader("Authorization") != valid {
+			c.String(401, "")
+			return
+		}
+
+		body, _ := c.GetRawData()
+		c.String(200, "ok:"+string(body))
+	})
+
+	return router
+}
+
+func Test_OAuth2_ClientCredentials(t *testing.T) {
+	s := &testOAuth2Server{expiresIn: 3600}
+	ts := httptest.NewServer(http.HandlerFunc(s.setup(t).ServeHTTP))
+	defer ts.Close()
+
+	o := NewOAuth2(OAuth2Config{
+		TokenURL:     ts.URL + "/token",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	})
+	g := New().SetOAuth2(o)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := ""
+			assert.NoError(t, g.POST(ts.URL+"/api").SetBody("hi").BindBody(&s).CheckStatus().Do())
+			assert.Equal(t, "ok:hi", s)
+		}()
+	}
+	wg.Wait()
+
+	// 并发请求只获取一次token
+	assert.Equal(t, int32(1), s.issued)
+	assert.Equal(t, []string{"client_credentials::read write"}, s.grants)
+
+	// 服务端吊销token, 收到401后刷新并重放一次
+	s.mu.Lock()
+	s.valid = "revoked"
+	s.mu.Unlock()
+
+	body := ""
+	assert.NoError(t, g.POST(ts.URL+"/api").SetJSON(H{"a": "b"}).BindBody(&body).CheckStatus().Do())
+	assert.Equal(t, "ok:{\"a\":\"b\"}\n", body)
+	assert.Equal(t, int32(2), s.issued)
+	assert.Equal(t, "refresh_token:refresh-1:read write", s.grants[1])
+
+	// 请求里设置的认证优先
+	code := 0
+	assert.NoError(t, g.POST(ts.URL+"/api").SetBearerToken("other").Code(&code).Do())
+	assert.Equal(t, 401, code)
+	assert.Equal(t, int32(2), s.issued)
+}
+
+// 重试的时候Authorization已经设置过了, 还是要使用oauth2, 收到401时刷新token
+func Test_OAuth2_Retry(t *testing.T) {
+	s := &testOAuth2Server{expiresIn: 3600}
+	ts := httptest.NewServer(http.HandlerFunc(s.setup(t).ServeHTTP))
+	defer ts.Close()
+
+	o := NewOAuth2(OAuth2Config{
+		TokenURL:     ts.URL + "/token",
+		ClientID:     "client",
+		ClientSecret: "secret",
+	})
+
+	// 第一次发送成功之后返回错误, 同时服务端吊销token
+	first := true
+	fail := func(next HandlerFunc) HandlerFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := next(req)
+			if first && err == nil {
+				first = false
+				resp.Body.Close()
+
+				s.mu.Lock()
+				s.valid = "revoked"
+				s.mu.Unlock()
+				return nil, errors.New("first fail")
+			}
+			return resp, err
+		}
+	}
+
+	body := ""
+	code := 0
+	err := New().Use(fail).SetOAuth2(o).
+		POST(ts.URL + "/api").
+		SetBody("retry").
+		BindBody(&body).
+		Code(&code).
+		Filter().
+		Retry().
+		Attempt(2).
+		WaitTime(time.Millisecond).
+		MaxWaitTime(time.Millisecond * 10).
+		Do()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "ok:retry", body)
+	assert.Equal(t, int32(2), s.issued)
+}
+
+func Test_OAuth2_Expiry(t *testing.T) {
+	s := &testOAuth2Server{expiresIn: 1}
+	ts := httptest.NewServer(http.HandlerFunc(s.setup(t).ServeHTTP))
+	defer ts.Close()
+
+	o := NewOAuth2(OAuth2Config{
+		TokenURL:     ts.URL + "/token",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RefreshToken: "saved",
+		ExpiryDelta:  time.Millisecond,
+	})
+
+	t1, err := o.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", t1.AccessToken)
+
+	t2, err := o.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, t1, t2)
+
+	// 过期之前主动刷新, 使用上一次返回的refresh_token
+	time.Sleep(time.Second)
+	t3, err := o.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", t3.AccessToken)
+	assert.Equal(t, []string{"refresh_token:saved:", "refresh_token:refresh-1:"}, s.grants)
+}
+
+func Test_OAuth2_Fail(t *testing.T) {
+	s := &testOAuth2Server{}
+	ts := httptest.NewServer(http.HandlerFunc(s.setup(t).ServeHTTP))
+	defer ts.Close()
+
+	o := NewOAuth2(OAuth2Config{
+		TokenURL:     ts.URL + "/token",
+		ClientID:     "client",
+		ClientSecret: "bad",
+	})
+
+	err := New().SetOAuth2(o).POST(ts.URL + "/api").Do()
+	e, ok := err.(*HTTPError)
+	assert.True(t, ok)
+	assert.Equal(t, 401, e.StatusCode)
+}
@@ -0,0 +1,62 @@
+package gout
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/guonaihong/gout/decode"
+	"github.com/guonaihong/gout/encode"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var errNoProtoMessage = errors.New("gout:protobuf debug needs a proto.Message")
+
+type protoBufCodec struct{}
+
+func (protoBufCodec) Encode(w io.Writer, obj interface{}) error {
+	return encode.NewProtoBufEncode(obj).Encode(w)
+}
+
+func (protoBufCodec) Decode(r io.Reader, obj interface{}) error {
+	return decode.DecodeProtoBuf(r, obj)
+}
+
+// DebugJSON 用obj的类型解析body, 转成protojson
+func (protoBufCodec) DebugJSON(body []byte, obj interface{}) ([]byte, error) {
+	msg, ok := obj.(proto.Message)
+	if !ok {
+		return nil, errNoProtoMessage
+	}
+
+	m := msg.ProtoReflect().New().Interface()
+	if err := proto.Unmarshal(body, m); err != nil {
+		return nil, err
+	}
+
+	all, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// protojson的输出里会随机加空格, 去掉方便阅读和比较
+	var out bytes.Buffer
+	err = json.Compact(&out, all)
+	return out.Bytes(), err
+}
+
+func init() {
+	RegisterCodec("protobuf", "application/x-protobuf", protoBufCodec{})
+}
+
+// SetProtoBuf 发送protobuf编码的body, Content-Type是application/x-protobuf
+func (df *DataFlow) SetProtoBuf(msg proto.Message) *DataFlow {
+	return df.SetCodec("protobuf", msg)
+}
+
+// BindProtoBuf 解析protobuf编码的body
+func (df *DataFlow) BindProtoBuf(msg proto.Message) *DataFlow {
+	return df.BindCodec("protobuf", msg)
+}
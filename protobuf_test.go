@@ -0,0 +1,60 @@
+package gout
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func Test_ProtoBuf(t *testing.T) {
+	router := gin.New()
+	router.POST("/echo", func(c *gin.Context) {
+		all, err := c.GetRawData()
+		assert.NoError(t, err)
+
+		msg := &structpb.Struct{}
+		assert.NoError(t, proto.Unmarshal(all, msg))
+		assert.Equal(t, "gout", msg.Fields["name"].GetStringValue())
+		c.Data(200, c.GetHeader("Content-Type"), all)
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	req, err := structpb.NewStruct(map[string]interface{}{"name": "gout", "stars": 100})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	var header struct {
+		ContentType string `header:"content-type"`
+	}
+	rsp := &structpb.Struct{}
+	err = POST(ts.URL + "/echo").
+		SetProtoBuf(req).
+		BindProtoBuf(rsp).
+		BindHeader(&header).
+		Debug(DebugFunc(func(o *DebugOption) {
+			o.Debug = true
+			o.Write = &buf
+		})).
+		Do()
+
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(req, rsp))
+	assert.Equal(t, "application/x-protobuf", header.ContentType)
+
+	// debug打印的是protojson, 请求和响应各一次
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte(`"name":"gout"`)), buf.String())
+
+	// BindAuto根据Content-Type选择protobuf, 没有目标类型时debug原样打印
+	rsp.Reset()
+	err = POST(ts.URL + "/echo").SetProtoBuf(req).BindAuto(rsp).Do()
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(req, rsp))
+}
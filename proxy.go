@@ -0,0 +1,304 @@
+package gout
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyFunc 根据请求选择代理, 返回nil表示直连
+// 支持http, https, socks5, socks5h. 用户名密码写在url里, http代理使用Basic认证
+type ProxyFunc func(req *http.Request) (*url.URL, error)
+
+// SetProxyFunc 设置Client级别的代理, 可以按host选择不同的代理, 请求里的SetProxy优先
+// 每个代理使用单独的Transport, 连接不会在不同的代理之间复用
+// 会复制一份Client, 不修改传入的Client和DefaultClient
+func (g *Gout) SetProxyFunc(f ProxyFunc) *Gout {
+	g.updateTransport(func(o *transportOpt) {
+		o.proxy = f
+	})
+	return g
+}
+
+// ProxyConfig 和HTTP_PROXY, HTTPS_PROXY, NO_PROXY环境变量的含义一样
+type ProxyConfig struct {
+	// http://, ws://的请求使用的代理, 为空时直连
+	HTTPProxy string
+	// https://, wss://的请求使用的代理, 为空时直连
+	HTTPSProxy string
+	// 逗号分隔, 匹配的host直连. *表示所有host
+	// example.com匹配它和它的子域名, .example.com只匹配子域名
+	// 可以带端口(example.com:8080), 也可以是ip或者cidr(10.0.0.0/8)
+	NoProxy string
+}
+
+// ProxyFromEnvironment 读取HTTP_PROXY, HTTPS_PROXY, NO_PROXY(或者小写)
+// 和http.ProxyFromEnvironment不同, 每次调用都重新读取, localhost不会自动直连
+func ProxyFromEnvironment() ProxyFunc {
+	c := &ProxyConfig{
+		HTTPProxy:  getEnvAny("HTTP_PROXY", "http_proxy"),
+		HTTPSProxy: getEnvAny("HTTPS_PROXY", "https_proxy"),
+		NoProxy:    getEnvAny("NO_PROXY", "no_proxy"),
+	}
+	return c.ProxyFunc()
+}
+
+func getEnvAny(names ...string) string {
+	for _, n := range names {
+		if v := os.Getenv(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ProxyFunc 把c转换成ProxyFunc, 可以传给SetProxyFunc
+func (c *ProxyConfig) ProxyFunc() ProxyFunc {
+	var httpProxy, httpsProxy *url.URL
+	var err error
+	if c.HTTPProxy != "" {
+		httpProxy, err = parseProxy(c.HTTPProxy)
+	}
+
+	if err == nil && c.HTTPSProxy != "" {
+		httpsProxy, err = parseProxy(c.HTTPSProxy)
+	}
+
+	noProxy := parseNoProxy(c.NoProxy)
+
+	return func(req *http.Request) (*url.URL, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		p := httpProxy
+		switch req.URL.Scheme {
+		case "https", "wss":
+			p = httpsProxy
+		}
+
+		if p == nil || noProxy.match(req.URL) {
+			return nil, nil
+		}
+		return p, nil
+	}
+}
+
+// parseProxy 没有scheme时当作http代理, :port是本机的端口
+func parseProxy(proxyURL string) (*url.URL, error) {
+	if !strings.Contains(proxyURL, "://") {
+		proxyURL = modifyURL(proxyURL)
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("gout:invalid proxy %q:%w", proxyURL, err)
+	}
+	return u, nil
+}
+
+// noProxyRule NO_PROXY里的一项
+type noProxyRule struct {
+	all    bool
+	ipNet  *net.IPNet
+	ip     net.IP
+	domain string // 以.开头
+	exact  bool   // 也匹配去掉.之后的domain
+	port   string
+}
+
+type noProxyRules []noProxyRule
+
+func parseNoProxy(noProxy string) (rules noProxyRules) {
+	for _, s := range strings.Split(noProxy, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+
+		if s == "*" {
+			return noProxyRules{{all: true}}
+		}
+
+		if _, ipNet, err := net.ParseCIDR(s); err == nil {
+			rules = append(rules, noProxyRule{ipNet: ipNet})
+			continue
+		}
+
+		host, port, err := net.SplitHostPort(s)
+		if err != nil {
+			host, port = s, ""
+		}
+
+		if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+			rules = append(rules, noProxyRule{ip: ip, port: port})
+			continue
+		}
+
+		r := noProxyRule{port: port, exact: true}
+		host = strings.TrimPrefix(host, "*")
+		if strings.HasPrefix(host, ".") {
+			r.exact = false
+		} else {
+			host = "." + host
+		}
+		r.domain = host
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+func (rules noProxyRules) match(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https", "wss":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+
+	ip := net.ParseIP(host)
+	for _, r := range rules {
+		switch {
+		case r.all:
+			return true
+		case r.ipNet != nil:
+			if ip != nil && r.ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		case r.port != "" && r.port != port:
+			continue
+		case r.ip != nil:
+			if r.ip.Equal(ip) {
+				return true
+			}
+		case strings.HasSuffix(host, r.domain) || r.exact && host == r.domain[1:]:
+			return true
+		}
+	}
+
+	return false
+}
+
+// setTransportProxy 让t通过p发送请求, t应该是一份副本
+func setTransportProxy(t *http.Transport, p *url.URL) error {
+	switch p.Scheme {
+	case "http", "https":
+		t.Proxy = http.ProxyURL(p)
+	case "socks5", "socks5h":
+		t.Proxy = nil
+		t.DialContext = socksDialer(p, transportDialer(t))
+		t.Dial = nil
+	default:
+		return fmt.Errorf("gout:unsupported proxy scheme %q", p.Scheme)
+	}
+
+	return nil
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (f dialFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+func (f dialFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+// socksDialer socks5在本地解析域名, socks5h把域名交给代理解析
+// forward是连接代理服务器使用的拨号函数
+func socksDialer(p *url.URL, forward dialFunc) dialFunc {
+	var auth *proxy.Auth
+	if p.User != nil {
+		password, _ := p.User.Password()
+		auth = &proxy.Auth{User: p.User.Username(), Password: password}
+	}
+
+	d, _ := proxy.SOCKS5("tcp", p.Host, auth, forward)
+	cd := d.(proxy.ContextDialer)
+	resolve := p.Scheme == "socks5"
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if resolve {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+
+			if net.ParseIP(host) == nil {
+				ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+				if err != nil {
+					return nil, err
+				}
+				addr = net.JoinHostPort(ips[0].String(), port)
+			}
+		}
+
+		return cd.DialContext(ctx, network, addr)
+	}
+}
+
+// proxyRouter 用ProxyFunc选择代理, 每个代理一个Transport
+type proxyRouter struct {
+	base       *http.Transport
+	proxy      ProxyFunc
+	transports sync.Map // 代理的url -> *http.Transport, 直连是""
+}
+
+func newProxyRouter(base *http.Transport, f ProxyFunc) *proxyRouter {
+	return &proxyRouter{base: base, proxy: f}
+}
+
+// transport 返回发送req使用的Transport
+func (r *proxyRouter) transport(req *http.Request) (*http.Transport, error) {
+	p, err := r.proxy(req)
+	if err != nil {
+		return nil, err
+	}
+
+	key := ""
+	if p != nil {
+		key = p.String()
+	}
+
+	if t, ok := r.transports.Load(key); ok {
+		return t.(*http.Transport), nil
+	}
+
+	t := r.base.Clone()
+	t.Proxy = nil
+	if p != nil {
+		if err = setTransportProxy(t, p); err != nil {
+			return nil, err
+		}
+	}
+
+	v, _ := r.transports.LoadOrStore(key, t)
+	return v.(*http.Transport), nil
+}
+
+func (r *proxyRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	t, err := r.transport(req)
+	if err != nil {
+		// RoundTripper返回错误时需要关闭body
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	return t.RoundTrip(req)
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"github.com/guonaihong/gout/decode"
 	"github.com/guonaihong/gout/encode"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strings"
 	"time"
@@ -29,14 +31,43 @@ type Req struct {
 	// query
 	queryEncode interface{}
 
+	// url path里的占位符
+	pathEncode interface{}
+
 	httpCode *int
 	g        *Gout
 
+	// 错误状态码的处理
+	checkStatus bool
+	errorCodes  []int
+	errDecoder  Decoder
+
+	// 按状态码分流的解析对象和回调
+	statusRoutes []statusRoute
+
+	// 每个请求独立的debug选项
+	opt DebugOption
+
 	callback func(*Context) error
 
 	//cookie
 	cookies []*http.Cookie
 
+	// basic, bearer, digest认证
+	auth *auth
+	// request()里确定的认证方式, send()使用
+	sendAuth   *auth
+	sendOAuth2 bool
+
+	signer Signer
+
+	compress *compressOpt
+
+	uploadProgress func(Progress)
+
+	// SetProxy, UnixSocket设置的路由, 和opt一样Reset之后保留
+	route *route
+
 	timeout time.Duration
 
 	//自增id，主要给互斥API定优先级
@@ -45,8 +76,10 @@ type Req struct {
 	timeoutIndex int
 	ctxIndex     int
 
-	c   context.Context
-	err error
+	c context.Context
+	// SetTimeout创建的context, 请求结束时取消
+	cancel context.CancelFunc
+	err    error
 }
 
 // req 结构布局说明，以decode为例
@@ -65,6 +98,21 @@ func (r *Req) Reset() {
 	r.headerDecode = nil
 	r.headerEncode = nil
 	r.queryEncode = nil
+	r.pathEncode = nil
+	r.checkStatus = false
+	r.errorCodes = nil
+	r.errDecoder = nil
+	r.statusRoutes = nil
+	r.uploadProgress = nil
+	r.auth = nil
+	r.sendAuth = nil
+	r.sendOAuth2 = false
+	r.signer = nil
+	r.compress = nil
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
 	r.c = nil
 }
 
@@ -87,13 +135,12 @@ func isString(x interface{}) (string, bool) {
 
 func (r *Req) addDefDebug() {
 	if r.bodyEncoder != nil {
-		switch bodyType := r.bodyEncoder.(Encoder); bodyType.Name() {
-		case "json":
-			r.g.opt.ReqBodyType = "json"
-		case "xml":
-			r.g.opt.ReqBodyType = "xml"
-		case "yaml":
-			r.g.opt.ReqBodyType = "yaml"
+		if name := r.bodyEncoder.Name(); getCodec(name) != nil {
+			r.opt.ReqBodyType = name
+		}
+
+		if c, ok := r.bodyEncoder.(*codecEncode); ok {
+			r.opt.reqBodyObj = c.obj
 		}
 	}
 
@@ -101,13 +148,13 @@ func (r *Req) addDefDebug() {
 
 func (r *Req) addContextType(req *http.Request) {
 	if r.bodyEncoder != nil {
-		switch bodyType := r.bodyEncoder.(Encoder); bodyType.Name() {
-		case "json":
-			req.Header.Add("Content-Type", "application/json")
-		case "xml":
-			req.Header.Add("Content-Type", "application/xml")
-		case "yaml":
-		case "www-form":
+		name := r.bodyEncoder.Name()
+		if mimeType, ok := codecContentType(name); ok {
+			req.Header.Add("Content-Type", mimeType)
+			return
+		}
+
+		if name == "www-form" {
 			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 		}
 	}
@@ -115,87 +162,248 @@ func (r *Req) addContextType(req *http.Request) {
 }
 
 func (r *Req) request() (*http.Request, error) {
-	body := &bytes.Buffer{}
+	// set url path
+	if r.pathEncode != nil {
+		p := encode.NewPathEncode()
+		if err := encode.Encode(r.pathEncode, p); err != nil {
+			return nil, err
+		}
 
-	// set http body
-	if r.bodyEncoder != nil {
-		if err := r.bodyEncoder.Encode(body); err != nil {
+		u, err := p.Replace(r.url)
+		if err != nil {
 			return nil, err
 		}
+		r.url = u
 	}
 
 	// set query header
-	if r.queryEncode != nil {
-		var query string
-		if q, ok := isString(r.queryEncode); ok {
-			query = q
-		} else {
-			q := encode.NewQueryEncode(nil)
-			if err := encode.Encode(r.queryEncode, q); err != nil {
-				return nil, err
-			}
+	query, err := r.encodeQuery()
+	if err != nil {
+		return nil, err
+	}
 
-			query = q.End()
-		}
+	if len(query) > 0 {
+		r.url += "?" + query
+	}
 
-		if len(query) > 0 {
-			r.url += "?" + query
-		}
+	req, err := http.NewRequest(r.method, r.url, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	var f *encode.FormEncode
+	_ = r.getContext()
+	if r.c != nil {
+		req = req.WithContext(r.c)
+	}
+
+	if r.route != nil {
+		req = req.WithContext(context.WithValue(req.Context(), routeKey{}, r.route))
+	}
+
+	r.addCookies(req)
+
+	// set http body
+	if err = r.setBody(req); err != nil {
+		return nil, err
+	}
+
+	// set http header
+	if err = r.encodeHeader(req); err != nil {
+		return nil, err
+	}
+
+	r.setAuth(req)
+	r.setAutoAccept(req)
+
+	r.addDefDebug()
+	r.addContextType(req)
+
+	if err = r.sign(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
 
+// setBody 设置http body
+// io.Reader和form-data直接流式发送, 其余的编码器先编码到内存里
+func (r *Req) setBody(req *http.Request) error {
 	// TODO
 	// 可以考虑和 bodyEncoder合并,
 	// 头疼的是f.FormDataContentType如何合并，每个encoder都实现这个方法???
 	if r.formEncode != nil {
-		f = encode.NewFormEncode(body)
-		if err := encode.Encode(r.formEncode, f); err != nil {
-			return nil, err
+		return setFormBody(req, r.formEncode, r.uploadProgress)
+	}
+
+	if err := r.setEncoderBody(req); err != nil {
+		return err
+	}
+
+	if r.uploadProgress != nil {
+		setBodyProgress(req, r.uploadProgress)
+	}
+
+	return nil
+}
+
+func (r *Req) setEncoderBody(req *http.Request) error {
+	if r.bodyEncoder == nil {
+		setBytesBody(req, nil)
+		return nil
+	}
+
+	if b, ok := r.bodyEncoder.(*encode.BodyEncode); ok {
+		if rd, ok := b.Reader(); ok {
+			if err := setReaderBody(req, rd); err != nil {
+				return err
+			}
+			return r.compressReaderBody(req)
 		}
+	}
 
-		f.End()
+	body := &bytes.Buffer{}
+	if err := r.bodyEncoder.Encode(body); err != nil {
+		return err
 	}
 
-	req, err := http.NewRequest(r.method, r.url, body)
+	all, err := r.compressBody(req, body.Bytes())
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	_ = r.getContext()
-	if r.c != nil {
-		req = req.WithContext(r.c)
+	setBytesBody(req, all)
+	return nil
+}
+
+func queryToStr(obj interface{}) (string, error) {
+	if q, ok := isString(obj); ok {
+		return q, nil
 	}
 
-	for _, c := range r.cookies {
-		req.AddCookie(c)
+	q := encode.NewQueryEncode(nil)
+	if err := encode.Encode(obj, q); err != nil {
+		return "", err
 	}
 
-	if r.formEncode != nil {
-		req.Header.Add("Content-Type", f.FormDataContentType())
+	return q.End(), nil
+}
+
+// 合并Gout里的默认query, 同名的key以请求里的为准
+func (r *Req) encodeQuery() (string, error) {
+	var query string
+	var err error
+
+	if r.queryEncode != nil {
+		if query, err = queryToStr(r.queryEncode); err != nil {
+			return "", err
+		}
 	}
 
-	// set http header
-	if r.headerEncode != nil {
-		err = encode.Encode(r.headerEncode, encode.NewHeaderEncode(req))
-		if err != nil {
-			return nil, err
+	if r.g == nil || r.g.defQuery == nil {
+		return query, nil
+	}
+
+	defQuery, err := queryToStr(r.g.defQuery)
+	if err != nil {
+		return "", err
+	}
+
+	values, err := url.ParseQuery(defQuery)
+	if err != nil {
+		return "", err
+	}
+
+	cur, err := url.ParseQuery(query)
+	if err != nil {
+		return "", err
+	}
+
+	for k, v := range cur {
+		values[k] = v
+	}
+
+	return values.Encode(), nil
+}
+
+// 合并Gout里的默认header, 同名的key以请求里的为准
+func (r *Req) encodeHeader(req *http.Request) error {
+	if r.g != nil && r.g.defHeader != nil {
+		if err := encode.Encode(r.g.defHeader, encode.NewHeaderEncode(req)); err != nil {
+			return err
 		}
 	}
 
-	r.addDefDebug()
-	r.addContextType(req)
-	return req, nil
+	if r.headerEncode == nil {
+		return nil
+	}
+
+	cur := &http.Request{Header: make(http.Header)}
+	if err := encode.Encode(r.headerEncode, encode.NewHeaderEncode(cur)); err != nil {
+		return err
+	}
+
+	for k, v := range cur.Header {
+		req.Header[k] = v
+	}
+
+	return nil
+}
+
+// 合并Gout里的默认cookie, 同名的cookie以请求里的为准
+func (r *Req) addCookies(req *http.Request) {
+	if r.g != nil {
+	next:
+		for _, def := range r.g.defCookies {
+			for _, c := range r.cookies {
+				if c.Name == def.Name {
+					continue next
+				}
+			}
+			req.AddCookie(def)
+		}
+	}
+
+	for _, c := range r.cookies {
+		req.AddCookie(c)
+	}
+}
+
+// keepOptions 新请求继承from的debug选项, SetProxy, UnixSocket, SetTimeout, WithContext
+func (r *Req) keepOptions(from *Req) {
+	r.opt = from.opt
+	r.opt.ReqBodyType, r.opt.RspBodyType = "", ""
+	r.opt.reqBodyObj, r.opt.rspBodyObj = nil, nil
+
+	// getRoute会修改route, 不能和from共用
+	if from.route != nil {
+		rt := *from.route
+		r.route = &rt
+	}
+
+	r.timeout, r.c = from.timeout, from.c
+	r.index, r.timeoutIndex, r.ctxIndex = from.index, from.timeoutIndex, from.ctxIndex
+}
+
+func (r *Req) getRoute() *route {
+	if r.route == nil {
+		r.route = &route{}
+	}
+	return r.route
 }
 
 func (r *Req) getContext() context.Context {
-	if r.timeout > 0 && r.timeoutIndex > r.ctxIndex {
-		r.c, _ = context.WithTimeout(context.Background(), r.timeout)
+	if r.timeout > 0 && r.timeoutIndex > r.ctxIndex && r.cancel == nil {
+		r.c, r.cancel = context.WithTimeout(context.Background(), r.timeout)
 	}
 	return r.c
 }
 
 func (r *Req) bind(req *http.Request, resp *http.Response) (err error) {
+	if err = decompressResponse(resp); err != nil {
+		return err
+	}
+
+	r.setAutoContentType(resp)
+
 	if r.headerDecode != nil {
 		err = decode.Header.Decode(resp, r.headerDecode)
 		if err != nil {
@@ -203,25 +411,37 @@ func (r *Req) bind(req *http.Request, resp *http.Response) (err error) {
 		}
 	}
 
-	if r.g.opt.Debug {
+	if r.opt.Debug {
+		if c, ok := r.bodyDecoder.(*codecDecode); ok {
+			r.opt.rspBodyObj = c.obj
+		}
+
 		// This is code(output debug info) be placed here
 		// all, err := ioutil.ReadAll(resp.Body)
 		// respBody  = bytes.NewReader(all)
-		if err := r.g.opt.resetBodyAndPrint(req, resp); err != nil {
+		if err := r.opt.resetBodyAndPrint(req, resp); err != nil {
 			return err
 		}
 	}
 
+	if r.httpCode != nil {
+		*r.httpCode = resp.StatusCode
+	}
+
+	if route := r.matchStatus(resp.StatusCode); route != nil {
+		return route.bind(resp)
+	}
+
+	if r.isErrorStatus(resp.StatusCode) {
+		return r.bindError(resp)
+	}
+
 	if r.bodyDecoder != nil {
 		if err := r.bodyDecoder.Decode(resp.Body); err != nil {
 			return err
 		}
 	}
 
-	if r.httpCode != nil {
-		*r.httpCode = resp.StatusCode
-	}
-
 	if r.callback != nil {
 		c := Context{Code: resp.StatusCode, Resp: resp}
 		if err := r.callback(&c); err != nil {
@@ -246,7 +466,7 @@ func (r *Req) Do() (err error) {
 		return err
 	}
 
-	resp, err := r.g.Client.Do(req)
+	resp, err := r.send(req)
 	if err != nil {
 		return err
 	}
@@ -256,11 +476,52 @@ func (r *Req) Do() (err error) {
 	return r.bind(req, resp)
 }
 
+// DoResponse 发送请求, body读到内存里, 返回*Response
+// 通过BindJSON等注册的解析对象也会照常生效
+func (r *Req) DoResponse() (rsp *Response, err error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// reset  Req
+	defer r.Reset()
+
+	req, err := r.request()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := r.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if err = decompressResponse(resp); err != nil {
+		return nil, err
+	}
+
+	all, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp = newResponse(req, resp, all, time.Since(start))
+	resp.Body = ioutil.NopCloser(bytes.NewReader(all))
+	return rsp, r.bind(req, resp)
+}
+
 func modifyURL(url string) string {
 	if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
 		return url
 	}
 
+	if strings.HasPrefix(url, "wss://") || strings.HasPrefix(url, "ws://") {
+		return url
+	}
+
 	if strings.HasPrefix(url, ":") {
 		return fmt.Sprintf("http://127.0.0.1%s", url)
 	}
@@ -272,6 +533,19 @@ func modifyURL(url string) string {
 	return fmt.Sprintf("http://%s", url)
 }
 
+func isAbsURL(url string) bool {
+	for _, proto := range absProtos {
+		if strings.HasPrefix(url, proto) {
+			return true
+		}
+	}
+	return false
+}
+
 func reqDef(method string, url string, g *Gout) Req {
+	if g != nil && len(g.baseURL) > 0 && !isAbsURL(url) {
+		url = joinPaths(g.baseURL, url)
+	}
+
 	return Req{method: method, url: modifyURL(url), g: g}
 }
@@ -0,0 +1,74 @@
+package gout
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/guonaihong/gout/decode"
+)
+
+// Response 是一次请求的完整结果
+// body已经读到内存里, 可以反复解析
+type Response struct {
+	StatusCode int
+	Status     string
+	Proto      string
+	Header     http.Header
+	Cookies    []*http.Cookie
+	Body       []byte
+
+	// 从发送请求到读完body的耗时
+	Elapsed time.Duration
+
+	// 跟随重定向之后的最终地址
+	URL *url.URL
+
+	// 原始请求
+	Request *http.Request
+}
+
+func newResponse(req *http.Request, resp *http.Response, body []byte, elapsed time.Duration) *Response {
+	rsp := &Response{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Proto:      resp.Proto,
+		Header:     resp.Header,
+		Cookies:    resp.Cookies(),
+		Body:       body,
+		Elapsed:    elapsed,
+		URL:        req.URL,
+		Request:    req,
+	}
+
+	if resp.Request != nil {
+		rsp.URL = resp.Request.URL
+	}
+
+	return rsp
+}
+
+func (r *Response) String() string {
+	return string(r.Body)
+}
+
+func (r *Response) BindBody(obj interface{}) error {
+	return decode.DecodeBody(bytes.NewReader(r.Body), obj)
+}
+
+func (r *Response) BindJSON(obj interface{}) error {
+	return decode.DecodeJSON(bytes.NewReader(r.Body), obj)
+}
+
+func (r *Response) BindYAML(obj interface{}) error {
+	return decode.DecodeYAML(bytes.NewReader(r.Body), obj)
+}
+
+func (r *Response) BindXML(obj interface{}) error {
+	return decode.DecodeXML(bytes.NewReader(r.Body), obj)
+}
+
+func (r *Response) BindHeader(obj interface{}) error {
+	return decode.Header.Decode(&http.Response{Header: r.Header}, obj)
+}
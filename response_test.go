@@ -0,0 +1,104 @@
+package gout
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupResponse(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	router.GET("/redirect", func(c *gin.Context) {
+		c.Redirect(302, "/json")
+	})
+
+	router.GET("/json", func(c *gin.Context) {
+		c.Header("sid", "sid-ok")
+		c.SetCookie("session", "session-ok", 3600, "/", "", false, false)
+		c.JSON(200, data{Id: 1, Data: "json"})
+	})
+
+	router.GET("/xml", func(c *gin.Context) {
+		c.XML(200, data{Id: 2, Data: "xml"})
+	})
+
+	router.GET("/yaml", func(c *gin.Context) {
+		c.YAML(200, data{Id: 3, Data: "yaml"})
+	})
+
+	return router
+}
+
+func Test_Response_Do(t *testing.T) {
+	router := setupResponse(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	var bindData data
+	rsp, err := GET(ts.URL + "/redirect").BindJSON(&bindData).DoResponse()
+	assert.NoError(t, err)
+
+	assert.Equal(t, 200, rsp.StatusCode)
+	assert.Equal(t, "200 OK", rsp.Status)
+	assert.Equal(t, "/json", rsp.URL.Path)
+	assert.Equal(t, "/redirect", rsp.Request.URL.Path)
+	assert.NotEqual(t, 0, int(rsp.Elapsed))
+	assert.Equal(t, data{Id: 1, Data: "json"}, bindData)
+
+	assert.Len(t, rsp.Cookies, 1)
+	assert.Equal(t, "session-ok", rsp.Cookies[0].Value)
+
+	// body可以多次解析
+	for i := 0; i < 2; i++ {
+		var d data
+		assert.NoError(t, rsp.BindJSON(&d))
+		assert.Equal(t, data{Id: 1, Data: "json"}, d)
+	}
+
+	s := ""
+	assert.NoError(t, rsp.BindBody(&s))
+	assert.Equal(t, rsp.String(), s)
+
+	header := struct {
+		Sid string `header:"sid"`
+	}{}
+	assert.NoError(t, rsp.BindHeader(&header))
+	assert.Equal(t, "sid-ok", header.Sid)
+
+	rsp, err = GET(ts.URL + "/xml").DoResponse()
+	assert.NoError(t, err)
+	var x data
+	assert.NoError(t, rsp.BindXML(&x))
+	assert.Equal(t, data{Id: 2, Data: "xml"}, x)
+
+	rsp, err = GET(ts.URL + "/yaml").DoResponse()
+	assert.NoError(t, err)
+	var y data
+	assert.NoError(t, rsp.BindYAML(&y))
+	assert.Equal(t, data{Id: 3, Data: "yaml"}, y)
+}
+
+func Test_Response_Fail(t *testing.T) {
+	router := setupResponse(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	// 解析失败, 也能拿到Response
+	var i int
+	rsp, err := GET(ts.URL + "/json").BindBody(&i).DoResponse()
+	assert.Error(t, err)
+	assert.NotNil(t, rsp)
+	assert.Equal(t, 200, rsp.StatusCode)
+
+	rsp, err = GET(ts.URL).SetQuery(1).DoResponse()
+	assert.Error(t, err)
+	assert.Nil(t, rsp)
+
+	rsp, err = GET(retry_doesNotExist).DoResponse()
+	assert.Error(t, err)
+	assert.Nil(t, rsp)
+}
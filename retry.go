@@ -79,6 +79,7 @@ func (r *Retry) getSleep() time.Duration {
 }
 
 func (r *Retry) Do() (err error) {
+	defer r.df.Req.Reset()
 	defer r.reset()
 	r.init()
 
@@ -87,17 +88,33 @@ func (r *Retry) Do() (err error) {
 		return err
 	}
 
+	// 不能Seek的io.Reader没有GetBody, 先读到内存里, 否则重试时发送的是空body
+	if r.attempt > 1 && req.GetBody == nil {
+		if err = bufferBody(req); err != nil {
+			return err
+		}
+	}
+
 	tk := time.NewTimer(r.maxWaitTime)
 	for i := 0; i < r.attempt; i++ {
 
-		resp, err := r.df.out.Client.Do(req)
+		// 上次发送已经读完了body, 重新获取一份
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			req.Body = body
+		}
+
+		resp, err := r.df.send(req)
 		if err == nil {
 			return r.df.bind(req, resp)
 		}
 
 		sleep := r.getSleep()
 
-		if r.df.out.opt.Debug {
+		if r.df.Req.opt.Debug {
 			fmt.Printf("filter:retry #current attempt:%d, wait time %v\n", r.currAttempt, sleep)
 		}
 
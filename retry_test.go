@@ -1,11 +1,15 @@
 package gout
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -108,3 +112,54 @@ func Test_Retry_Do(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func Test_Retry_GetBody(t *testing.T) {
+	router := setupBody(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	// 第一次发送失败之前body已经被读完
+	first := true
+	fail := func(next HandlerFunc) HandlerFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if first {
+				first = false
+				ioutil.ReadAll(req.Body)
+				return nil, errors.New("first fail")
+			}
+			return next(req)
+		}
+	}
+
+	var rsp testBodyRsp
+	err := New().Use(fail).
+		POST(ts.URL + "/body").
+		SetBody(strings.NewReader("retry body")).
+		BindJSON(&rsp).
+		Filter().
+		Retry().
+		Attempt(2).
+		WaitTime(time.Millisecond).
+		MaxWaitTime(time.Millisecond * 10).
+		Do()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "retry body", rsp.Body)
+
+	// 不能Seek的io.Reader
+	first = true
+	rsp = testBodyRsp{}
+	err = New().Use(fail).
+		POST(ts.URL + "/body").
+		SetBody(struct{ io.Reader }{strings.NewReader("stream body")}).
+		BindJSON(&rsp).
+		Filter().
+		Retry().
+		Attempt(2).
+		WaitTime(time.Millisecond).
+		MaxWaitTime(time.Millisecond * 10).
+		Do()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "stream body", rsp.Body)
+}
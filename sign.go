@@ -0,0 +1,349 @@
+package gout
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrSignBody = errors.New("gout:signer needs a replayable body(GetBody)")
+
+// Signer 在请求编码完成之后调用, 可以读取GetBody计算body的摘要
+// 一般用来设置Authorization之类的签名header
+type Signer interface {
+	Sign(req *http.Request) error
+}
+
+// SignerFunc 函数形式的Signer
+type SignerFunc func(req *http.Request) error
+
+func (f SignerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
+
+// SetSigner 设置签名, 在header, body, 认证都设置好之后调用
+func (df *DataFlow) SetSigner(s Signer) *DataFlow {
+	df.Req.signer = s
+	return df
+}
+
+// SetDefaultSigner 设置默认签名, 请求里的SetSigner会覆盖它
+func (g *Gout) SetDefaultSigner(s Signer) *Gout {
+	g.defSigner = s
+	return g
+}
+
+func (r *Req) sign(req *http.Request) error {
+	s := r.signer
+	if s == nil && r.g != nil {
+		s = r.g.defSigner
+	}
+
+	if s == nil {
+		return nil
+	}
+
+	return s.Sign(req)
+}
+
+// HashBody 通过GetBody计算body的sha256, 返回hex编码. 没有body时是空串的sha256
+// 读完之后重新设置req.Body, 自定义的Signer可以直接使用
+func HashBody(req *http.Request) (string, error) {
+	h := sha256.New()
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return "", ErrSignBody
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+
+		_, err = io.Copy(h, body)
+		body.Close()
+		if err != nil {
+			return "", err
+		}
+
+		if err = resetBody(req); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CanonicalRequest 按照SigV4的格式生成规范请求
+// METHOD\nPATH\nQUERY\nHEADERS\nSIGNED_HEADERS\nBODY_HASH
+// headers是参与签名的header名, host会自动加上. query不合法时返回错误
+func CanonicalRequest(req *http.Request, headers []string, bodyHash string) (canonical, signed string, err error) {
+	query, err := canonicalQuery(req.URL)
+	if err != nil {
+		return "", "", err
+	}
+
+	names := []string{"host"}
+	for _, h := range headers {
+		h = strings.ToLower(h)
+		if h != "host" {
+			names = append(names, h)
+		}
+	}
+	sort.Strings(names)
+	names = dedup(names)
+
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteByte('\n')
+	b.WriteString(canonicalPath(req.URL))
+	b.WriteByte('\n')
+	b.WriteString(query)
+	b.WriteByte('\n')
+
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(':')
+		if name == "host" {
+			b.WriteString(requestHost(req))
+		} else {
+			vals := req.Header[http.CanonicalHeaderKey(name)]
+			for j, v := range vals {
+				if j > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(strings.Join(strings.Fields(v), " "))
+			}
+		}
+		b.WriteByte('\n')
+	}
+
+	signed = strings.Join(names, ";")
+	b.WriteByte('\n')
+	b.WriteString(signed)
+	b.WriteByte('\n')
+	b.WriteString(bodyHash)
+	return b.String(), signed, nil
+}
+
+// dedup 去掉排好序的s里重复的元素
+func dedup(s []string) []string {
+	out := s[:0:0]
+	for i, v := range s {
+		if i > 0 && s[i-1] == v {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// RFC 3986 unreserved之外的字符都编码, 空格编码成%20
+func escapeRFC3986(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&15])
+	}
+	return b.String()
+}
+
+func canonicalPath(u *url.URL) string {
+	p := u.Path
+	if p == "" {
+		return "/"
+	}
+
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = escapeRFC3986(s)
+	}
+	return strings.Join(segs, "/")
+}
+
+// canonicalQuery 先按编码之后的key排序, key相同再按value排序
+// 不能直接排序k=v, a=1和a1=2的顺序会反
+func canonicalQuery(u *url.URL) (string, error) {
+	q, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(q))
+	escaped := make(map[string]string, len(q))
+	for k := range q {
+		ek := escapeRFC3986(k)
+		keys = append(keys, ek)
+		escaped[ek] = k
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(q))
+	for _, ek := range keys {
+		vals := make([]string, 0, len(q[escaped[ek]]))
+		for _, v := range q[escaped[ek]] {
+			vals = append(vals, escapeRFC3986(v))
+		}
+		sort.Strings(vals)
+
+		for _, v := range vals {
+			pairs = append(pairs, ek+"="+v)
+		}
+	}
+	return strings.Join(pairs, "&"), nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	io.WriteString(h, data)
+	return h.Sum(nil)
+}
+
+func sha256Hex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// HMACSigner 对规范请求做HMAC-SHA256签名
+// Authorization: HMAC-SHA256 Credential=<KeyID>, SignedHeaders=<headers>, Signature=<hex>
+type HMACSigner struct {
+	KeyID  string
+	Secret []byte
+
+	// 参与签名的header, host总是参与签名
+	SignedHeaders []string
+
+	// 不为空时把当前时间(20060102T150405Z)写到这个header, 并参与签名
+	DateHeader string
+
+	// 保存签名的header, 默认Authorization
+	Header string
+
+	now func() time.Time
+}
+
+func (s *HMACSigner) Sign(req *http.Request) error {
+	bodyHash, err := HashBody(req)
+	if err != nil {
+		return err
+	}
+
+	headers := s.SignedHeaders
+	if s.DateHeader != "" {
+		req.Header.Set(s.DateHeader, signTime(s.now).Format(amzDateFormat))
+		headers = append(headers[:len(headers):len(headers)], s.DateHeader)
+	}
+
+	canonical, signed, err := CanonicalRequest(req, headers, bodyHash)
+	if err != nil {
+		return err
+	}
+	sig := hex.EncodeToString(hmacSHA256(s.Secret, canonical))
+
+	header := s.Header
+	if header == "" {
+		header = "Authorization"
+	}
+
+	req.Header.Set(header, "HMAC-SHA256 Credential="+s.KeyID+", SignedHeaders="+signed+", Signature="+sig)
+	return nil
+}
+
+const (
+	amzDateFormat  = "20060102T150405Z"
+	amzShortFormat = "20060102"
+)
+
+func signTime(now func() time.Time) time.Time {
+	if now == nil {
+		return time.Now().UTC()
+	}
+	return now().UTC()
+}
+
+// AWSSigner AWS Signature Version 4签名
+type AWSSigner struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	Service      string
+
+	// 设置x-amz-content-sha256, S3需要
+	ContentSHA256 bool
+
+	now func() time.Time
+}
+
+// 这些header可能被代理或者Transport修改, 不参与签名
+var awsUnsignedHeaders = map[string]bool{
+	"Authorization":   true,
+	"User-Agent":      true,
+	"X-Amzn-Trace-Id": true,
+	"Expect":          true,
+	"Content-Length":  true,
+}
+
+func (s *AWSSigner) Sign(req *http.Request) error {
+	bodyHash, err := HashBody(req)
+	if err != nil {
+		return err
+	}
+
+	t := signTime(s.now)
+	req.Header.Set("X-Amz-Date", t.Format(amzDateFormat))
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	if s.ContentSHA256 {
+		req.Header.Set("X-Amz-Content-Sha256", bodyHash)
+	}
+
+	headers := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		if !awsUnsignedHeaders[k] {
+			headers = append(headers, k)
+		}
+	}
+
+	canonical, signed, err := CanonicalRequest(req, headers, bodyHash)
+	if err != nil {
+		return err
+	}
+
+	date := t.Format(amzShortFormat)
+	scope := date + "/" + s.Region + "/" + s.Service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + t.Format(amzDateFormat) + "\n" + scope + "\n" + sha256Hex(canonical)
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+sig)
+	return nil
+}
@@ -0,0 +1,191 @@
+package gout
+
+import (
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 4231 4.3 test case 2
+func Test_Sign_HMACSHA256(t *testing.T) {
+	sum := hmacSHA256([]byte("Jefe"), "what do ya want for nothing?")
+	assert.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", hex.EncodeToString(sum))
+}
+
+func testAWSSigner() *AWSSigner {
+	return &AWSSigner{
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:    "us-east-1",
+		Service:   "service",
+		now: func() time.Time {
+			return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+		},
+	}
+}
+
+// aws-sig-v4-test-suite
+func Test_Sign_AWSTestSuite(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		method    string
+		url       string
+		body      string
+		header    http.Header
+		signed    string
+		signature string
+	}{
+		{
+			name:      "get-vanilla",
+			method:    "GET",
+			url:       "https://example.amazonaws.com/",
+			signed:    "host;x-amz-date",
+			signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:      "get-vanilla-query-order-key-case",
+			method:    "GET",
+			url:       "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			signed:    "host;x-amz-date",
+			signature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			// 和get-vanilla-query-order-key-case相同的请求, 只是key互为前缀, 签名按SigV4规范另外计算
+			name:      "get-vanilla-query-order-key-prefix",
+			method:    "GET",
+			url:       "https://example.amazonaws.com/?Param10=value10&Param1=value1",
+			signed:    "host;x-amz-date",
+			signature: "b52a808868447c24463111b8297b1b508238bc3e4853abe218ae9af46fc24310",
+		},
+		{
+			name:      "post-vanilla",
+			method:    "POST",
+			url:       "https://example.amazonaws.com/",
+			signed:    "host;x-amz-date",
+			signature: "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:      "post-x-www-form-urlencoded",
+			method:    "POST",
+			url:       "https://example.amazonaws.com/",
+			body:      "Param1=value1",
+			header:    http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}},
+			signed:    "content-type;host;x-amz-date",
+			signature: "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	} {
+		req, err := http.NewRequest(tc.method, tc.url, nil)
+		assert.NoError(t, err)
+		if tc.body != "" {
+			setBytesBody(req, []byte(tc.body))
+		}
+		for k, v := range tc.header {
+			req.Header[k] = v
+		}
+
+		assert.NoError(t, testAWSSigner().Sign(req), tc.name)
+		assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+		assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders="+tc.signed+", Signature="+tc.signature, req.Header.Get("Authorization"), tc.name)
+	}
+}
+
+func Test_Sign_CanonicalRequest(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.com/a b/c?b=2&a=1&a=0&sp=x%20y", nil)
+	req.Header.Set("X-Test", "  a   b ")
+	req.Header.Add("X-Multi", "1")
+	req.Header.Add("X-Multi", "2")
+
+	c, signed, err := CanonicalRequest(req, []string{"X-Test", "x-multi", "Host"}, sha256Hex(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "host;x-multi;x-test", signed)
+	assert.Equal(t, "GET\n/a%20b/c\na=0&a=1&b=2&sp=x%20y\n"+
+		"host:example.com\nx-multi:1,2\nx-test:a b\n\n"+
+		"host;x-multi;x-test\n"+
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", c)
+}
+
+func Test_Sign_CanonicalQuery(t *testing.T) {
+	u, _ := url.Parse("http://example.com/?a1=2&a=1&b=y&b=x")
+	q, err := canonicalQuery(u)
+	assert.NoError(t, err)
+	assert.Equal(t, "a=1&a1=2&b=x&b=y", q)
+
+	req, _ := http.NewRequest("GET", "http://example.com/?a=%zz", nil)
+	_, _, err = CanonicalRequest(req, nil, sha256Hex(""))
+	assert.Error(t, err)
+	assert.Error(t, testAWSSigner().Sign(req))
+}
+
+func Test_Sign_Dataflow(t *testing.T) {
+	signer := &HMACSigner{
+		KeyID:         "key",
+		Secret:        []byte("secret"),
+		SignedHeaders: []string{"Content-Type"},
+		DateHeader:    "X-Date",
+	}
+
+	// 服务端用同样的规范请求校验签名
+	router := gin.New()
+	router.POST("/sign", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		canonical, signed, _ := CanonicalRequest(c.Request, []string{"Content-Type", "X-Date"}, sha256Hex(string(body)))
+		want := "HMAC-SHA256 Credential=key, SignedHeaders=" + signed + ", Signature=" +
+			hex.EncodeToString(hmacSHA256([]byte("secret"), canonical))
+
+		if c.GetHeader("Authorization") != want || c.GetHeader("X-Date") == "" {
+			c.String(401, "")
+			return
+		}
+		c.String(200, string(body))
+	})
+
+	router.GET("/header", func(c *gin.Context) {
+		c.String(200, c.GetHeader("X-Sign")+"|"+c.GetHeader("Authorization"))
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	s := ""
+	code := 0
+	g := New().SetDefaultSigner(signer)
+	err := g.POST(ts.URL + "/sign").SetJSON(H{"a": "b"}).BindBody(&s).Code(&code).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "{\"a\":\"b\"}\n", s)
+
+	// 请求里的SetSigner优先
+	err = g.GET(ts.URL + "/header").
+		SetSigner(SignerFunc(func(req *http.Request) error {
+			req.Header.Set("X-Sign", "func")
+			return nil
+		})).
+		BindBody(&s).
+		Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "func|", s)
+
+	// SetBody(io.Reader)的body计算摘要之后还要能发送
+	for _, body := range []io.Reader{
+		strings.NewReader("reader body"),
+		struct{ io.ReadSeeker }{strings.NewReader("reader body")},
+	} {
+		err = g.POST(ts.URL + "/sign").SetBody(body).BindBody(&s).Code(&code).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, 200, code)
+		assert.Equal(t, "reader body", s)
+	}
+
+	// 不能重放的body返回错误
+	err = g.POST(ts.URL + "/sign").SetBody(struct{ io.Reader }{strings.NewReader("x")}).Do()
+	assert.Equal(t, ErrSignBody, err)
+}
@@ -0,0 +1,261 @@
+package gout
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guonaihong/gout/decode"
+)
+
+// 服务端没有发送retry:时的重连间隔
+var DefaultSSERetry = 3 * time.Second
+
+// Event text/event-stream里的一个事件
+type Event struct {
+	ID    string
+	Event string // 没有event:时是message
+	Data  []byte
+}
+
+// BindJSON 把Data当作json解析到obj里
+func (e *Event) BindJSON(obj interface{}) error {
+	return decode.DecodeJSON(bytes.NewReader(e.Data), obj)
+}
+
+// SSE Server-Sent Events客户端
+// 连接断开之后带上Last-Event-ID重连, 直到WithContext设置的context结束
+type SSE struct {
+	df      *DataFlow
+	handler func(*Event) error
+	ch      chan<- *Event
+
+	retry        time.Duration
+	maxReconnect int
+	lastID       string
+}
+
+func (df *DataFlow) SSE() *SSE {
+	return &SSE{df: df, retry: DefaultSSERetry, maxReconnect: -1}
+}
+
+// Handler 每收到一个事件调用一次, 返回错误时结束Do
+func (s *SSE) Handler(cb func(*Event) error) *SSE {
+	s.handler = cb
+	return s
+}
+
+// Chan 把事件发送到ch里, Do返回时关闭ch
+func (s *SSE) Chan(ch chan<- *Event) *SSE {
+	s.ch = ch
+	return s
+}
+
+// Retry 设置重连间隔, 服务端的retry:会覆盖它
+func (s *SSE) Retry(d time.Duration) *SSE {
+	s.retry = d
+	return s
+}
+
+// MaxReconnect 最多重连n次, 用完之后返回最后一次的错误, 小于0时一直重连
+func (s *SSE) MaxReconnect(n int) *SSE {
+	s.maxReconnect = n
+	return s
+}
+
+// LastEventID 第一次连接时就带上Last-Event-ID
+func (s *SSE) LastEventID(id string) *SSE {
+	s.lastID = id
+	return s
+}
+
+// Do 连接并处理事件, context结束或者服务端返回204时返回nil
+// 非200的状态码和Handler返回的错误不会重连
+func (s *SSE) Do() (err error) {
+	defer s.df.Req.Reset()
+	if s.ch != nil {
+		defer close(s.ch)
+	}
+
+	if s.df.Req.err != nil {
+		return s.df.Req.err
+	}
+
+	req, err := s.df.Req.request()
+	if err != nil {
+		return err
+	}
+
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+
+	ctx := req.Context()
+	for reconnect := 1; ; reconnect++ {
+		err = s.connect(req)
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+
+		if stop, ok := err.(*sseStop); ok {
+			return stop.err
+		}
+
+		if s.maxReconnect >= 0 && reconnect > s.maxReconnect {
+			return err
+		}
+
+		if opt := &s.df.Req.opt; opt.Debug {
+			w := opt.Write
+			if w == nil {
+				w = os.Stdout
+			}
+			fmt.Fprintf(w, "sse:reconnect #%d after %v, last error:%v\n", reconnect, s.retry, err)
+		}
+
+		tk := time.NewTimer(s.retry)
+		select {
+		case <-tk.C:
+		case <-ctx.Done():
+			tk.Stop()
+			return nil
+		}
+	}
+}
+
+// sseStop 不需要重连的错误
+type sseStop struct {
+	err error
+}
+
+func (s *sseStop) Error() string {
+	return s.err.Error()
+}
+
+// connect 建立一次连接并读取事件, 返回nil表示不再重连
+func (s *SSE) connect(req *http.Request) (err error) {
+	req = req.Clone(req.Context())
+	if req.GetBody != nil {
+		if req.Body, err = req.GetBody(); err != nil {
+			return &sseStop{err}
+		}
+	}
+
+	if s.lastID != "" {
+		req.Header.Set("Last-Event-ID", s.lastID)
+	}
+
+	resp, err := s.df.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil
+	default:
+		return &sseStop{fmt.Errorf("sse:unexpected status %s", resp.Status)}
+	}
+
+	if err = decompressResponse(resp); err != nil {
+		return err
+	}
+
+	if err = s.read(req.Context(), resp.Body); err == nil {
+		err = io.EOF
+	}
+	return err
+}
+
+// read 按照https://html.spec.whatwg.org/multipage/server-sent-events.html解析事件流
+// id:先保存在idBuf里, 事件分发的时候才更新lastID, 没有结束的事件不会影响Last-Event-ID
+func (s *SSE) read(ctx context.Context, r io.Reader) error {
+	br := bufio.NewReader(r)
+	var data bytes.Buffer
+	eventType := ""
+	idBuf := s.lastID
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			// 没有以空行结束的事件丢弃
+			return err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			s.lastID = idBuf
+			if data.Len() == 0 {
+				eventType = ""
+				continue
+			}
+
+			e := &Event{ID: s.lastID, Event: eventType}
+			if e.Event == "" {
+				e.Event = "message"
+			}
+			e.Data = append(e.Data, bytes.TrimSuffix(data.Bytes(), []byte("\n"))...)
+
+			data.Reset()
+			eventType = ""
+
+			if err := s.dispatch(ctx, e); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if pos := strings.IndexByte(line, ':'); pos != -1 {
+			field, value = line[:pos], strings.TrimPrefix(line[pos+1:], " ")
+		}
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				idBuf = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+func (s *SSE) dispatch(ctx context.Context, e *Event) error {
+	if s.handler != nil {
+		if err := s.handler(e); err != nil {
+			return &sseStop{err}
+		}
+	}
+
+	if s.ch != nil {
+		select {
+		case s.ch <- e:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
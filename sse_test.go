@@ -0,0 +1,137 @@
+package gout
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SSE_Reconnect(t *testing.T) {
+	var conns int32
+	var lastIDs []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+		switch atomic.AddInt32(&conns, 1) {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, ": comment\n\n")
+			// 服务端把重连间隔改成10ms
+			fmt.Fprint(w, "retry: 10\n")
+			fmt.Fprint(w, "id: 1\ndata: {\"n\":1}\n\n")
+			fmt.Fprint(w, "event: user\r\nid: 2\r\ndata: {\"n\":\r\ndata: 2}\r\n\r\n")
+			// 没有空行结束, 丢弃
+			fmt.Fprint(w, "id: 3\ndata: lost")
+		case 2:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data:{\"n\":3}\n\n")
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	defer ts.Close()
+
+	var events []Event
+	var ns []int
+	err := GET(ts.URL).SSE().Retry(time.Hour).Handler(func(e *Event) error {
+		var v struct{ N int }
+		assert.NoError(t, e.BindJSON(&v))
+		ns = append(ns, v.N)
+		events = append(events, *e)
+		return nil
+	}).Do()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, ns)
+	assert.Equal(t, "message", events[0].Event)
+	assert.Equal(t, "1", events[0].ID)
+	assert.Equal(t, "user", events[1].Event)
+	assert.Equal(t, "{\"n\":\n2}", string(events[1].Data))
+	// 没有id:的事件沿用上一个id
+	assert.Equal(t, "2", events[2].ID)
+	assert.Equal(t, []string{"", "2", "2"}, lastIDs)
+}
+
+func Test_SSE_ChanContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "id: %d\ndata: %d\n\n", i, i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan *Event)
+	done := make(chan error)
+	go func() {
+		done <- GET(ts.URL).WithContext(ctx).SSE().Chan(ch).Do()
+	}()
+
+	for i := 0; i < 3; i++ {
+		e := <-ch
+		assert.Equal(t, fmt.Sprint(i), string(e.Data))
+	}
+	cancel()
+
+	// Do返回nil并且关闭ch
+	assert.NoError(t, <-done)
+	for range ch {
+	}
+}
+
+func Test_SSE_Stop(t *testing.T) {
+	var conns int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&conns, 1)
+		if r.URL.Path == "/404" {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprint(w, "data: x\n\n")
+	}))
+	defer ts.Close()
+
+	// 非200不重连
+	err := GET(ts.URL + "/404").SSE().Do()
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), conns)
+
+	// Handler返回的错误不重连
+	stop := errors.New("stop")
+	err = GET(ts.URL).SSE().Handler(func(e *Event) error { return stop }).Do()
+	assert.Equal(t, stop, err)
+	assert.Equal(t, int32(2), conns)
+
+	// 每次连接都收到了事件, 重连次数用完之后还是返回最后一次的错误
+	var buf bytes.Buffer
+	debug := DebugFunc(func(o *DebugOption) {
+		o.Debug = true
+		o.Write = &buf
+	})
+	err = GET(ts.URL).Debug(debug).SSE().Retry(time.Millisecond).MaxReconnect(2).Do()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, int32(5), conns)
+	assert.Contains(t, buf.String(), "sse:reconnect #2")
+}
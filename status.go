@@ -0,0 +1,109 @@
+package gout
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/guonaihong/gout/decode"
+)
+
+// statusRoute 状态码在[min, max]之间时, body交给decoder或者callback处理
+type statusRoute struct {
+	min, max int
+	decoder  Decoder
+	callback func(*Context) error
+}
+
+// parseStatus 解析状态码或者范围
+// code 可以是 404, "404", "4xx", "400-499"
+func parseStatus(code interface{}) (min, max int, err error) {
+	switch c := code.(type) {
+	case int:
+		return c, c, nil
+	case string:
+		c = strings.ToLower(strings.TrimSpace(c))
+		if len(c) == 3 && strings.HasSuffix(c, "xx") && c[0] >= '1' && c[0] <= '5' {
+			min = int(c[0]-'0') * 100
+			return min, min + 99, nil
+		}
+
+		if pos := strings.IndexByte(c, '-'); pos != -1 {
+			if min, err = strconv.Atoi(c[:pos]); err != nil {
+				return 0, 0, err
+			}
+
+			if max, err = strconv.Atoi(c[pos+1:]); err != nil {
+				return 0, 0, err
+			}
+
+			if min > max {
+				return 0, 0, fmt.Errorf("gout:invalid status range %q", c)
+			}
+			return min, max, nil
+		}
+
+		min, err = strconv.Atoi(c)
+		return min, min, err
+	}
+
+	return 0, 0, fmt.Errorf("gout:unknown status type %T", code)
+}
+
+func (df *DataFlow) addStatusRoute(code interface{}, route statusRoute) *DataFlow {
+	min, max, err := parseStatus(code)
+	if err != nil {
+		df.Req.err = err
+		return df
+	}
+
+	route.min, route.max = min, max
+	df.Req.statusRoutes = append(df.Req.statusRoutes, route)
+	return df
+}
+
+// OnStatus 状态码匹配时调用cb, 不再走BindJSON和Callback
+// 多个匹配时, 先设置的优先
+func (df *DataFlow) OnStatus(code interface{}, cb func(*Context) error) *DataFlow {
+	return df.addStatusRoute(code, statusRoute{callback: cb})
+}
+
+// BindStatusJSON 状态码匹配时, body解析到obj里
+func (df *DataFlow) BindStatusJSON(code interface{}, obj interface{}) *DataFlow {
+	return df.addStatusRoute(code, statusRoute{decoder: newCodecDecode("json", obj)})
+}
+
+func (df *DataFlow) BindStatusXML(code interface{}, obj interface{}) *DataFlow {
+	return df.addStatusRoute(code, statusRoute{decoder: newCodecDecode("xml", obj)})
+}
+
+func (df *DataFlow) BindStatusYAML(code interface{}, obj interface{}) *DataFlow {
+	return df.addStatusRoute(code, statusRoute{decoder: newCodecDecode("yaml", obj)})
+}
+
+func (df *DataFlow) BindStatusBody(code interface{}, obj interface{}) *DataFlow {
+	return df.addStatusRoute(code, statusRoute{decoder: decode.NewBodyDecode(obj)})
+}
+
+func (r *Req) matchStatus(code int) *statusRoute {
+	for i := range r.statusRoutes {
+		if route := &r.statusRoutes[i]; code >= route.min && code <= route.max {
+			return route
+		}
+	}
+
+	return nil
+}
+
+func (s *statusRoute) bind(resp *http.Response) error {
+	if s.decoder != nil {
+		return s.decoder.Decode(resp.Body)
+	}
+
+	if s.callback != nil {
+		return s.callback(&Context{Code: resp.StatusCode, Resp: resp})
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package gout
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupStatus(t *testing.T) *gin.Engine {
+	router := gin.New()
+
+	router.GET("/:code", func(c *gin.Context) {
+		switch code := c.Param("code"); code {
+		case "200":
+			c.JSON(200, data{Id: 200, Data: "ok"})
+		case "201":
+			c.XML(201, data{Id: 201, Data: "created"})
+		case "404":
+			c.JSON(404, testAPIError{ErrMsg: "not found", ErrCode: 404})
+		case "500", "503":
+			c.String(500, "server fail")
+		}
+	})
+
+	return router
+}
+
+func Test_Status_Route(t *testing.T) {
+	router := setupStatus(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	for _, path := range []string{"/200", "/404", "/500"} {
+		var ok data
+		var notFound testAPIError
+		var fail string
+		called := 0
+
+		err := GET(ts.URL+path).
+			BindStatusJSON("2xx", &ok).
+			BindStatusJSON(404, &notFound).
+			OnStatus("500-599", func(c *Context) error {
+				called++
+				return c.BindBody(&fail)
+			}).
+			Do()
+
+		assert.NoError(t, err)
+		switch path {
+		case "/200":
+			assert.Equal(t, data{Id: 200, Data: "ok"}, ok)
+			assert.Equal(t, testAPIError{}, notFound)
+			assert.Equal(t, 0, called)
+		case "/404":
+			assert.Equal(t, data{}, ok)
+			assert.Equal(t, testAPIError{ErrMsg: "not found", ErrCode: 404}, notFound)
+		case "/500":
+			assert.Equal(t, 1, called)
+			assert.Equal(t, "server fail", fail)
+		}
+	}
+
+	// 没有匹配的状态码走默认的解析
+	var x data
+	var ok data
+	err := GET(ts.URL+"/201").BindStatusJSON(200, &ok).BindXML(&x).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, data{Id: 201, Data: "created"}, x)
+	assert.Equal(t, data{}, ok)
+
+	// 先设置的优先
+	x = data{}
+	err = GET(ts.URL+"/201").BindStatusXML("201", &x).BindStatusJSON("2xx", &ok).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, data{Id: 201, Data: "created"}, x)
+
+	// 回调返回错误
+	err = GET(ts.URL+"/500").OnStatus("5xx", func(c *Context) error {
+		return errors.New("5xx")
+	}).Do()
+	assert.EqualError(t, err, "5xx")
+
+	s := ""
+	err = GET(ts.URL+"/404").BindStatusBody(404, &s).BindStatusYAML(200, &ok).Do()
+	assert.NoError(t, err)
+	assert.Contains(t, s, "not found")
+}
+
+func Test_Status_parse(t *testing.T) {
+	tests := []struct {
+		code     interface{}
+		min, max int
+	}{
+		{404, 404, 404},
+		{"404", 404, 404},
+		{"2xx", 200, 299},
+		{"5XX", 500, 599},
+		{"400-499", 400, 499},
+	}
+
+	for _, v := range tests {
+		min, max, err := parseStatus(v.code)
+		assert.NoError(t, err)
+		assert.Equal(t, v.min, min)
+		assert.Equal(t, v.max, max)
+	}
+
+	for _, code := range []interface{}{"abc", "499-400", "a-499", "400-b", 4.04} {
+		_, _, err := parseStatus(code)
+		assert.Error(t, err)
+	}
+
+	err := GET("127.0.0.1").OnStatus("abc", nil).Do()
+	assert.Error(t, err)
+}
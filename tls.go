@@ -0,0 +1,200 @@
+package gout
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+var ErrSPKIPinMismatch = errors.New("gout:no certificate matches the pinned public key")
+
+// TLSConfig 构造*tls.Config, 出错之后后面的设置都不生效, 错误由Config返回
+//
+//	cfg, err := gout.NewTLSConfig().
+//		LoadCA("ca.pem").
+//		LoadClientCert("client.pem", "client.key").
+//		Config()
+type TLSConfig struct {
+	cfg  *tls.Config
+	pins [][]byte
+	err  error
+}
+
+func NewTLSConfig() *TLSConfig {
+	return &TLSConfig{cfg: &tls.Config{}}
+}
+
+// LoadCA 读取PEM格式的CA证书, 使用它们代替系统的根证书
+func (t *TLSConfig) LoadCA(pemFile ...string) *TLSConfig {
+	for _, f := range pemFile {
+		if t.err != nil {
+			return t
+		}
+
+		pem, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.err = err
+			return t
+		}
+
+		t.addCA(pem, f)
+	}
+
+	return t
+}
+
+// AddCAPEM 和LoadCA一样, 证书在内存里
+func (t *TLSConfig) AddCAPEM(pem []byte) *TLSConfig {
+	return t.addCA(pem, "pem")
+}
+
+func (t *TLSConfig) addCA(pem []byte, name string) *TLSConfig {
+	if t.err != nil {
+		return t
+	}
+
+	if t.cfg.RootCAs == nil {
+		t.cfg.RootCAs = x509.NewCertPool()
+	}
+
+	if !t.cfg.RootCAs.AppendCertsFromPEM(pem) {
+		t.err = fmt.Errorf("gout:no certificates found in %s", name)
+	}
+	return t
+}
+
+// LoadClientCert 读取PEM格式的客户端证书和私钥, 用于mTLS
+func (t *TLSConfig) LoadClientCert(certFile, keyFile string) *TLSConfig {
+	if t.err != nil {
+		return t
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	t.cfg.Certificates = append(t.cfg.Certificates, cert)
+	return t
+}
+
+// ClientCertPEM 和LoadClientCert一样, 证书和私钥在内存里
+func (t *TLSConfig) ClientCertPEM(certPEM, keyPEM []byte) *TLSConfig {
+	if t.err != nil {
+		return t
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	t.cfg.Certificates = append(t.cfg.Certificates, cert)
+	return t
+}
+
+// InsecureSkipVerify 不验证服务端的证书链和域名
+// 同时设置了PinSPKI时只检查服务端证书(leaf)的公钥
+func (t *TLSConfig) InsecureSkipVerify() *TLSConfig {
+	t.cfg.InsecureSkipVerify = true
+	return t
+}
+
+// PinSPKI 服务端证书链里至少有一个证书的公钥和pin一致, 否则握手失败
+// pin是SubjectPublicKeyInfo的sha256的base64编码, 可以带sha256/前缀, 可以用SPKIHash计算
+func (t *TLSConfig) PinSPKI(pin ...string) *TLSConfig {
+	for _, p := range pin {
+		if t.err != nil {
+			return t
+		}
+
+		h, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(p, "sha256/"))
+		if err != nil || len(h) != sha256.Size {
+			t.err = fmt.Errorf("gout:invalid spki pin %q", p)
+			return t
+		}
+
+		t.pins = append(t.pins, h)
+	}
+
+	return t
+}
+
+// ServerName 设置SNI, 也是验证证书时使用的域名
+func (t *TLSConfig) ServerName(name string) *TLSConfig {
+	t.cfg.ServerName = name
+	return t
+}
+
+// Config 返回*tls.Config, 每次调用返回一个新的副本
+func (t *TLSConfig) Config() (*tls.Config, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	cfg := t.cfg.Clone()
+	if len(t.pins) > 0 {
+		pins := t.pins
+		skipVerify := cfg.InsecureSkipVerify
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			return verifySPKIPins(pins, rawCerts, verifiedChains, skipVerify)
+		}
+	}
+
+	return cfg, nil
+}
+
+// verifySPKIPins 验证过的证书链里任意一个证书匹配就通过
+// 没有验证证书链时其他证书可以随便伪造, 只有leaf的私钥在握手里被证明过
+func verifySPKIPins(pins [][]byte, rawCerts [][]byte, verifiedChains [][]*x509.Certificate, skipVerify bool) error {
+	var certs []*x509.Certificate
+	if skipVerify {
+		if len(rawCerts) == 0 {
+			return ErrSPKIPinMismatch
+		}
+
+		leaf, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		certs = append(certs, leaf)
+	}
+
+	for _, chain := range verifiedChains {
+		certs = append(certs, chain...)
+	}
+
+	for _, c := range certs {
+		h := sha256.Sum256(c.RawSubjectPublicKeyInfo)
+		for _, p := range pins {
+			if subtle.ConstantTimeCompare(h[:], p) == 1 {
+				return nil
+			}
+		}
+	}
+
+	return ErrSPKIPinMismatch
+}
+
+// SPKIHash 返回证书公钥的sha256的base64编码, 可以直接传给PinSPKI
+func SPKIHash(cert *x509.Certificate) string {
+	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// SetTLSConfig 设置Client的TLS配置, cfg可以由NewTLSConfig().Config()生成
+// 会复制一份Client和Transport, 不修改传入的Client和DefaultClient
+func (g *Gout) SetTLSConfig(cfg *tls.Config) *Gout {
+	g.updateTransport(func(o *transportOpt) {
+		o.tlsConfig = cfg
+	})
+	return g
+}
@@ -0,0 +1,221 @@
+package gout
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testTLSServer(t *testing.T, cfg *tls.Config) (*httptest.Server, []byte) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := ""
+		if len(r.TLS.PeerCertificates) > 0 {
+			name = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		w.Write([]byte(r.TLS.ServerName + "|" + name))
+	}))
+	ts.TLS = cfg
+	ts.StartTLS()
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	return ts, caPEM
+}
+
+// testClientCert 生成自签名的客户端证书, 返回PEM格式的证书和私钥
+func testClientCert(t *testing.T, cn string) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func testWriteFile(t *testing.T, data []byte) string {
+	f, err := ioutil.TempFile("", "gout-tls")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write(data)
+	assert.NoError(t, err)
+	return f.Name()
+}
+
+func Test_TLS_CA(t *testing.T) {
+	ts, caPEM := testTLSServer(t, nil)
+	defer ts.Close()
+
+	// 不信任httptest的证书
+	err := New(&http.Client{}).GET(ts.URL).Do()
+	assert.Error(t, err)
+
+	caFile := testWriteFile(t, caPEM)
+	defer os.Remove(caFile)
+
+	for _, b := range []*TLSConfig{
+		NewTLSConfig().LoadCA(caFile),
+		NewTLSConfig().AddCAPEM(caPEM),
+		NewTLSConfig().InsecureSkipVerify(),
+	} {
+		cfg, err := b.Config()
+		assert.NoError(t, err)
+
+		var s string
+		err = New(&http.Client{}).SetTLSConfig(cfg).GET(ts.URL).BindBody(&s).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, "|", s)
+	}
+
+	_, err = NewTLSConfig().LoadCA("not-found.pem").Config()
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig().AddCAPEM([]byte("not pem")).Config()
+	assert.Error(t, err)
+}
+
+func Test_TLS_ClientCert(t *testing.T) {
+	certPEM, keyPEM := testClientCert(t, "client")
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+
+	ts, caPEM := testTLSServer(t, &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	})
+	defer ts.Close()
+
+	certFile, keyFile := testWriteFile(t, certPEM), testWriteFile(t, keyPEM)
+	defer os.Remove(certFile)
+	defer os.Remove(keyFile)
+
+	for _, b := range []*TLSConfig{
+		NewTLSConfig().AddCAPEM(caPEM).LoadClientCert(certFile, keyFile),
+		NewTLSConfig().AddCAPEM(caPEM).ClientCertPEM(certPEM, keyPEM),
+	} {
+		cfg, err := b.Config()
+		assert.NoError(t, err)
+
+		var s string
+		err = New(&http.Client{}).SetTLSConfig(cfg).GET(ts.URL).BindBody(&s).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, "|client", s)
+	}
+
+	// 没有客户端证书
+	cfg, err := NewTLSConfig().AddCAPEM(caPEM).Config()
+	assert.NoError(t, err)
+	err = New(&http.Client{}).SetTLSConfig(cfg).GET(ts.URL).Do()
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig().ClientCertPEM(certPEM, []byte("bad key")).Config()
+	assert.Error(t, err)
+}
+
+func Test_TLS_PinSPKI(t *testing.T) {
+	ts, caPEM := testTLSServer(t, nil)
+	defer ts.Close()
+
+	pin := SPKIHash(ts.Certificate())
+	otherCert, _ := testClientCert(t, "other")
+	block, _ := pem.Decode(otherCert)
+	other, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+
+	for _, d := range []struct {
+		b  *TLSConfig
+		ok bool
+	}{
+		{NewTLSConfig().AddCAPEM(caPEM).PinSPKI(pin), true},
+		{NewTLSConfig().AddCAPEM(caPEM).PinSPKI(SPKIHash(other), "sha256/"+pin), true},
+		{NewTLSConfig().InsecureSkipVerify().PinSPKI(pin), true},
+		{NewTLSConfig().AddCAPEM(caPEM).PinSPKI(SPKIHash(other)), false},
+		{NewTLSConfig().InsecureSkipVerify().PinSPKI(SPKIHash(other)), false},
+	} {
+		cfg, err := d.b.Config()
+		assert.NoError(t, err)
+
+		err = New(&http.Client{}).SetTLSConfig(cfg).GET(ts.URL).Do()
+		if d.ok {
+			assert.NoError(t, err)
+		} else {
+			assert.True(t, errors.Is(err, ErrSPKIPinMismatch), "%v", err)
+		}
+	}
+
+	_, err = NewTLSConfig().PinSPKI("not base64!").Config()
+	assert.Error(t, err)
+}
+
+func Test_TLS_ServerName(t *testing.T) {
+	ts, caPEM := testTLSServer(t, nil)
+	defer ts.Close()
+
+	// httptest的证书包含example.com
+	cfg, err := NewTLSConfig().AddCAPEM(caPEM).ServerName("example.com").Config()
+	assert.NoError(t, err)
+
+	var s string
+	err = New(&http.Client{}).SetTLSConfig(cfg).GET(ts.URL).BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com|", s)
+
+	cfg, err = NewTLSConfig().AddCAPEM(caPEM).ServerName("other.com").Config()
+	assert.NoError(t, err)
+	err = New(&http.Client{}).SetTLSConfig(cfg).GET(ts.URL).Do()
+	assert.Error(t, err)
+}
+
+// SetTLSConfig和SetHTTP2的顺序不影响结果, 不修改传入的Client
+func Test_TLS_WithHTTP2(t *testing.T) {
+	ts := httptest.NewUnstartedServer(testHTTP2Handler())
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	cfg, err := NewTLSConfig().AddCAPEM(caPEM).Config()
+	assert.NoError(t, err)
+
+	c := &http.Client{}
+	for _, g := range []*Gout{
+		New(c).SetTLSConfig(cfg).SetHTTP2(HTTP2Only),
+		New(c).SetHTTP2(HTTP2Only).SetTLSConfig(cfg),
+	} {
+		var s string
+		err = g.GET(ts.URL).BindBody(&s).Do()
+		assert.NoError(t, err)
+		assert.Equal(t, "HTTP/2.0", s)
+	}
+
+	assert.Nil(t, c.Transport)
+}
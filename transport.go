@@ -0,0 +1,260 @@
+package gout
+
+import (
+	"container/list"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// transportOpt SetTLSConfig, SetHTTP2这些会影响连接的设置
+// 每次修改之后从base重新生成Transport, 设置的先后顺序不影响结果
+type transportOpt struct {
+	// 第一次修改之前Client的*http.Transport的副本
+	base *http.Transport
+	// base加上tlsConfig, websocket使用它
+	h1 *http.Transport
+
+	tlsConfig *tls.Config
+	http2Mode HTTP2Mode
+
+	proxy  ProxyFunc
+	router *proxyRouter
+
+	// Client.Transport不是*http.Transport, 每个请求都返回这个错误
+	err error
+}
+
+// updateTransport 复制一份Client, 不修改传入的Client和DefaultClient
+// Client.Transport不是*http.Transport时不修改Client, 后面的请求返回错误
+func (g *Gout) updateTransport(set func(o *transportOpt)) {
+	o := &g.transport
+	if o.err != nil {
+		return
+	}
+
+	if o.base == nil {
+		t, err := g.h1Transport(g.Client)
+		if err != nil {
+			o.err = err
+			return
+		}
+		o.base = t.Clone()
+	}
+
+	set(o)
+
+	o.h1 = o.base.Clone()
+	if o.tlsConfig != nil {
+		o.h1.TLSClientConfig = o.tlsConfig
+	}
+
+	var h1 http.RoundTripper = o.h1
+	o.router = nil
+	if o.proxy != nil {
+		o.router = newProxyRouter(o.h1, o.proxy)
+		h1 = o.router
+	}
+
+	rt := h1
+	if o.http2Mode != 0 {
+		rt = newHTTP2Transport(o.http2Mode, o.h1.Clone(), h1)
+	}
+
+	c := *g.Client
+	c.Transport = rt
+	g.Client = &c
+}
+
+// transportDialer 返回Transport的拨号函数, UnixSocket设置的是Dial
+func transportDialer(t *http.Transport) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if t.Dial != nil {
+		return func(_ context.Context, network, addr string) (net.Conn, error) {
+			return t.Dial(network, addr)
+		}
+	}
+
+	if t.DialContext != nil {
+		return t.DialContext
+	}
+
+	var d net.Dialer
+	return d.DialContext
+}
+
+// route 单个请求的代理和UnixSocket设置, request()把它放到context里, Gout.do根据它选择Transport
+type route struct {
+	proxy      *url.URL
+	unixSocket string
+}
+
+type routeKey struct{}
+
+// routeTransportKey 相同的Transport加上相同的route共用一个Transport, 保留连接池
+type routeTransportKey struct {
+	base       *http.Transport
+	proxy      string
+	unixSocket string
+}
+
+type routedTransport struct {
+	h1 *http.Transport
+	rt http.RoundTripper
+}
+
+func (t *routedTransport) closeIdleConnections() {
+	closeIdleConnections(t.h1)
+	closeIdleConnections(t.rt)
+}
+
+// closeIdleConnections 和http.Client.CloseIdleConnections一样, rt没有这个方法时什么也不做
+func closeIdleConnections(rt http.RoundTripper) {
+	if c, ok := rt.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// 每个Gout最多缓存的Transport个数
+const maxRouteTransports = 32
+
+// routeCache routeTransportKey -> *routedTransport
+// 超过maxRouteTransports时淘汰最久没有使用的, 并关闭它的空闲连接
+type routeCache struct {
+	mu    sync.Mutex
+	ll    *list.List
+	items map[routeTransportKey]*list.Element
+}
+
+type routeCacheEntry struct {
+	key routeTransportKey
+	t   *routedTransport
+}
+
+func newRouteCache() *routeCache {
+	return &routeCache{ll: list.New(), items: make(map[routeTransportKey]*list.Element)}
+}
+
+// 没有传入Client的New()共用一个缓存, 比如gout.GET(url).SetProxy(proxy)
+var defaultRouteCache = newRouteCache()
+
+func (c *routeCache) get(key routeTransportKey) (*routedTransport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.ll.MoveToFront(e)
+	return e.Value.(*routeCacheEntry).t, true
+}
+
+// add 已经有相同key的Transport时返回它
+func (c *routeCache) add(key routeTransportKey, t *routedTransport) *routedTransport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*routeCacheEntry).t
+	}
+
+	c.items[key] = c.ll.PushFront(&routeCacheEntry{key: key, t: t})
+	for c.ll.Len() > maxRouteTransports {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		old := e.Value.(*routeCacheEntry)
+		delete(c.items, old.key)
+		old.t.closeIdleConnections()
+	}
+
+	return t
+}
+
+// h1Transport 返回c的*http.Transport, SetHTTP2之后是HTTP/1.1的那个
+func (g *Gout) h1Transport(c *http.Client) (*http.Transport, error) {
+	if c == g.Client && g.transport.h1 != nil {
+		return g.transport.h1, nil
+	}
+
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("gout:not found http.transport:%T", rt)
+	}
+	return t, nil
+}
+
+// routeTransport 复制c的Transport, 再设置r的代理和UnixSocket. 不修改c的Transport
+func (g *Gout) routeTransport(c *http.Client, r *route) (*routedTransport, error) {
+	base, err := g.h1Transport(c)
+	if err != nil {
+		return nil, err
+	}
+
+	key := routeTransportKey{base: base, unixSocket: r.unixSocket}
+	if r.proxy != nil {
+		key.proxy = r.proxy.String()
+	}
+
+	if t, ok := g.routes.get(key); ok {
+		return t, nil
+	}
+
+	t := base.Clone()
+	if r.proxy != nil {
+		if err = setTransportProxy(t, r.proxy); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.unixSocket != "" {
+		path := r.unixSocket
+		t.Dial = nil
+		t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+	}
+
+	rt := &routedTransport{h1: t, rt: t}
+	if c == g.Client && g.transport.http2Mode != 0 {
+		rt.rt = newHTTP2Transport(g.transport.http2Mode, t.Clone(), t)
+	}
+
+	return g.routes.add(key, rt), nil
+}
+
+// client 返回发送req使用的Client, 请求设置了代理或者UnixSocket时是一份副本
+func (g *Gout) client(req *http.Request) (*http.Client, error) {
+	if g.transport.err != nil {
+		return nil, g.transport.err
+	}
+
+	r, ok := req.Context().Value(routeKey{}).(*route)
+	if !ok {
+		return g.Client, nil
+	}
+
+	return g.routeClient(g.Client, r)
+}
+
+func (g *Gout) routeClient(c *http.Client, r *route) (*http.Client, error) {
+	t, err := g.routeTransport(c, r)
+	if err != nil {
+		return nil, err
+	}
+
+	c2 := *c
+	c2.Transport = t.rt
+	return &c2, nil
+}
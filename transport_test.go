@@ -0,0 +1,176 @@
+package gout
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testNameServer 返回自己的名字, 当作代理时也不转发
+func testNameServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}))
+}
+
+// SetProxy, UnixSocket不修改DefaultClient, 不影响后面的请求
+func Test_Route_NotModifyClient(t *testing.T) {
+	ts := testNameServer("direct")
+	defer ts.Close()
+	proxy := testNameServer("proxy")
+	defer proxy.Close()
+
+	var s string
+	err := GET(ts.URL).SetProxy(proxy.URL).BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "proxy", s)
+	assert.Nil(t, DefaultClient.Transport)
+
+	err = GET(ts.URL).BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "direct", s)
+
+	path := "./route.sock"
+	defer os.Remove(path)
+	l, err := net.Listen("unix", path)
+	assert.NoError(t, err)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("unix"))
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	err = GET(ts.URL).UnixSocket(path).BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "unix", s)
+	assert.Nil(t, DefaultClient.Transport)
+
+	err = GET(ts.URL).BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "direct", s)
+}
+
+// 同一个Client上并发使用不同的代理
+func Test_Route_Concurrent(t *testing.T) {
+	ts := testNameServer("direct")
+	defer ts.Close()
+	proxyA := testNameServer("a")
+	defer proxyA.Close()
+	proxyB := testNameServer("b")
+	defer proxyB.Close()
+
+	g := New(&http.Client{})
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			df, want := g.GET(ts.URL), "direct"
+			switch i % 3 {
+			case 1:
+				df, want = df.SetProxy(proxyA.URL), "a"
+			case 2:
+				df, want = df.SetProxy(proxyB.URL), "b"
+			}
+
+			var s string
+			err := df.BindBody(&s).Do()
+			assert.NoError(t, err)
+			assert.Equal(t, want, s)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// 相同的设置复用同一个Transport, 保留连接池
+func Test_Route_TransportCache(t *testing.T) {
+	g := New(&http.Client{})
+	r := &route{unixSocket: "./cache.sock"}
+
+	t1, err := g.routeTransport(g.Client, r)
+	assert.NoError(t, err)
+	t2, err := g.routeTransport(g.Client, &route{unixSocket: "./cache.sock"})
+	assert.NoError(t, err)
+	assert.True(t, t1 == t2)
+
+	t3, err := g.routeTransport(g.Client, &route{unixSocket: "./other.sock"})
+	assert.NoError(t, err)
+	assert.True(t, t1 != t3)
+
+	// 缓存在Gout上, 不同的Gout不共用
+	g2 := New(g.Client)
+	t4, err := g2.routeTransport(g2.Client, r)
+	assert.NoError(t, err)
+	assert.True(t, t1 != t4)
+
+	g = New(&http.Client{Transport: &TransportFail{}})
+	_, err = g.routeTransport(g.Client, r)
+	assert.Error(t, err)
+}
+
+// 缓存的Transport有上限, 淘汰的Transport关闭空闲连接
+func Test_Route_TransportCacheEvict(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	proxy := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxy"))
+	}))
+	proxy.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	proxy.Start()
+	defer proxy.Close()
+
+	g := New(&http.Client{})
+	var s string
+	err := g.GET("http://example.com").SetProxy(proxy.URL).BindBody(&s).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, "proxy", s)
+
+	for i := 0; i < maxRouteTransports; i++ {
+		_, err = g.routeTransport(g.Client, &route{unixSocket: fmt.Sprintf("./evict-%d.sock", i)})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, maxRouteTransports, g.routes.ll.Len())
+	assert.Len(t, g.routes.items, maxRouteTransports)
+
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("idle connection of evicted transport not closed")
+	}
+}
+
+// Client.Transport不是*http.Transport时不偷偷换成DefaultTransport, 请求返回错误
+func Test_Transport_CustomRoundTripper(t *testing.T) {
+	ts := testNameServer("direct")
+	defer ts.Close()
+
+	cfg, err := NewTLSConfig().InsecureSkipVerify().Config()
+	assert.NoError(t, err)
+
+	rt := &TransportFail{}
+	for _, g := range []*Gout{
+		New(&http.Client{Transport: rt}).SetTLSConfig(cfg),
+		New(&http.Client{Transport: rt}).SetHTTP2(H2CPriorKnowledge),
+		New(&http.Client{Transport: rt}).SetProxyFunc(ProxyFromEnvironment()),
+	} {
+		assert.True(t, g.Client.Transport == rt)
+
+		err = g.GET(ts.URL).Do()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not found http.transport")
+
+		_, err = g.GET(ts.URL).WebSocket()
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,132 @@
+package gout
+
+import (
+	"io"
+	"net/http"
+)
+
+// Progress 上传进度, 长度未知时是-1
+type Progress struct {
+	// multipart里当前part的字段名和文件名, 其他编码为空
+	FieldName string
+	FileName  string
+	PartDone  int64
+	PartTotal int64
+
+	// 整个body
+	Done  int64
+	Total int64
+}
+
+// UploadProgress 设置上传进度回调, body写到连接上时调用
+// form-data的回调在编码的goroutine里执行
+func (df *DataFlow) UploadProgress(cb func(Progress)) *DataFlow {
+	df.Req.uploadProgress = cb
+	return df
+}
+
+type progressReader struct {
+	r  io.ReadCloser
+	p  Progress
+	cb func(Progress)
+}
+
+func (p *progressReader) Read(b []byte) (n int, err error) {
+	n, err = p.r.Read(b)
+	if n > 0 {
+		p.p.Done += int64(n)
+		p.cb(p.p)
+	}
+	return n, err
+}
+
+func (p *progressReader) Close() error {
+	return p.r.Close()
+}
+
+func (p *progressReader) withoutProgress() io.ReadCloser {
+	return p.r
+}
+
+// noProgressBody debug打印请求body时去掉进度回调, 打印不是发送, 不应该再走一遍进度
+type noProgressBody interface {
+	withoutProgress() io.ReadCloser
+}
+
+// formProgressBody 带进度的form-data body, withoutProgress重新编码一份不带进度的
+type formProgressBody struct {
+	io.ReadCloser
+	raw func() io.ReadCloser
+}
+
+func (f *formProgressBody) withoutProgress() io.ReadCloser {
+	f.ReadCloser.Close()
+	return f.raw()
+}
+
+// setBodyProgress 包装body, 统计读取(写到连接上)的字节数
+func setBodyProgress(req *http.Request, cb func(Progress)) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	total := req.ContentLength
+	if total == 0 {
+		total = -1
+	}
+
+	wrap := func(r io.ReadCloser) io.ReadCloser {
+		return &progressReader{r: r, p: Progress{Total: total, PartTotal: -1}, cb: cb}
+	}
+
+	req.Body = wrap(req.Body)
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			r, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return wrap(r), nil
+		}
+	}
+}
+
+// formProgress 在写端统计multipart的进度
+// 管道的Write要等读端全部读走才返回, 所以写入的字节数就是发送的字节数
+type formProgress struct {
+	w       io.Writer
+	p       Progress
+	cb      func(Progress)
+	partNow bool
+}
+
+func (f *formProgress) Write(b []byte) (n int, err error) {
+	n, err = f.w.Write(b)
+	if n > 0 {
+		f.p.Done += int64(n)
+		if f.partNow {
+			f.p.PartDone += int64(n)
+		}
+		f.cb(f.p)
+	}
+	return n, err
+}
+
+func (f *formProgress) partWriter(fieldName, fileName string, size int64, w io.Writer) io.Writer {
+	f.p.FieldName = fieldName
+	f.p.FileName = fileName
+	f.p.PartDone = 0
+	f.p.PartTotal = size
+	return &formPartWriter{w: w, f: f}
+}
+
+type formPartWriter struct {
+	w io.Writer
+	f *formProgress
+}
+
+func (p *formPartWriter) Write(b []byte) (int, error) {
+	p.f.partNow = true
+	defer func() { p.f.partNow = false }()
+	return p.w.Write(b)
+}
@@ -0,0 +1,141 @@
+package gout
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/guonaihong/gout/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Upload_Progress(t *testing.T) {
+	router := setupBody(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	tests := []func(df *DataFlow) *DataFlow{
+		func(df *DataFlow) *DataFlow { return df.SetJSON(H{"hello": "world"}) },
+		func(df *DataFlow) *DataFlow { return df.SetWWWForm(H{"hello": "world"}) },
+		func(df *DataFlow) *DataFlow { return df.SetBody("hello world") },
+	}
+
+	for _, set := range tests {
+		var last Progress
+		var rsp testBodyRsp
+		err := set(POST(ts.URL + "/body")).
+			UploadProgress(func(p Progress) {
+				assert.True(t, p.Done > last.Done)
+				last = p
+			}).
+			BindJSON(&rsp).
+			Do()
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(rsp.Body)), last.Done)
+		assert.Equal(t, last.Done, last.Total)
+		assert.Equal(t, int64(-1), last.PartTotal)
+	}
+
+	// 长度未知
+	var last Progress
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("pipe body"))
+		pw.Close()
+	}()
+
+	err := POST(ts.URL + "/body").SetBody(pr).UploadProgress(func(p Progress) { last = p }).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), last.Done)
+	assert.Equal(t, int64(-1), last.Total)
+}
+
+func Test_Upload_FormProgress(t *testing.T) {
+	router := setupBody(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	fi, err := os.Stat("testdata/voice.pcm")
+	assert.NoError(t, err)
+
+	var last Progress
+	parts := map[string]Progress{}
+	err = POST(ts.URL + "/form").
+		SetForm(H{"mode": "A", "voice": core.FormFile("testdata/voice.pcm")}).
+		UploadProgress(func(p Progress) {
+			assert.True(t, p.Done > last.Done)
+			last = p
+			if p.PartTotal >= 0 {
+				parts[p.FieldName] = p
+			}
+		}).
+		Do()
+
+	assert.NoError(t, err)
+	assert.Equal(t, last.Total, last.Done)
+
+	voice := parts["voice"]
+	assert.Equal(t, "voice", voice.FileName)
+	assert.Equal(t, fi.Size(), voice.PartTotal)
+	assert.Equal(t, fi.Size(), voice.PartDone)
+
+	mode := parts["mode"]
+	assert.Equal(t, int64(1), mode.PartTotal)
+	assert.Equal(t, int64(1), mode.PartDone)
+}
+
+func Test_Upload_GetBody(t *testing.T) {
+	req, err := http.NewRequest("POST", "/", nil)
+	assert.NoError(t, err)
+
+	setBytesBody(req, []byte("get body"))
+
+	n := 0
+	setBodyProgress(req, func(p Progress) { n++ })
+
+	body, err := req.GetBody()
+	assert.NoError(t, err)
+	all, err := ioutil.ReadAll(body)
+	assert.NoError(t, err)
+	assert.NoError(t, body.Close())
+	assert.Equal(t, "get body", string(all))
+	assert.NotEqual(t, 0, n)
+}
+
+// debug打印请求body不能再触发一遍进度回调
+func Test_Upload_ProgressDebug(t *testing.T) {
+	router := setupBody(t)
+	ts := httptest.NewServer(http.HandlerFunc(router.ServeHTTP))
+	defer ts.Close()
+
+	debug := DebugFunc(func(o *DebugOption) {
+		o.Debug = true
+		o.Write = ioutil.Discard
+	})
+
+	tests := []func(df *DataFlow) *DataFlow{
+		func(df *DataFlow) *DataFlow { return df.SetJSON(H{"hello": "world"}) },
+		func(df *DataFlow) *DataFlow {
+			return df.SetForm(H{"mode": "A", "voice": core.FormFile("testdata/voice.pcm")})
+		},
+	}
+
+	for _, set := range tests {
+		passes := 0
+		err := set(POST(ts.URL + "/body")).
+			UploadProgress(func(p Progress) {
+				if p.Done == p.Total {
+					passes++
+				}
+			}).
+			Debug(debug).
+			Do()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, passes)
+	}
+}
@@ -10,8 +10,13 @@ import (
 const (
 	httpProto  = "http://"
 	httpsProto = "https://"
+	wsProto    = "ws://"
+	wssProto   = "wss://"
 )
 
+// absProtos 以这些开头的url是绝对地址, 不拼接SetBaseURL
+var absProtos = []string{httpProto, httpsProto, wsProto, wssProto}
+
 type ReadCloseFail = core.ReadCloseFail
 
 type H = core.H
@@ -34,14 +39,11 @@ func lastChar(str string) uint8 {
 func join(elem ...string) (rv string) {
 
 	defer func() {
-		if strings.HasPrefix(rv, httpProto) {
-			rv = httpProto + path.Clean(rv[len(httpProto):])
-			return
-		}
-
-		if strings.HasPrefix(rv, httpsProto) {
-			rv = httpsProto + path.Clean(rv[len(httpsProto):])
-			return
+		for _, proto := range absProtos {
+			if strings.HasPrefix(rv, proto) {
+				rv = proto + path.Clean(rv[len(proto):])
+				return
+			}
 		}
 
 		rv = path.Clean(rv)
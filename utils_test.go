@@ -36,6 +36,8 @@ func TestUtil_joinPaths(t *testing.T) {
 		{absolutePath: "www.bb.com", relativePath: "/b", need: "www.bb.com/b"},
 		{absolutePath: "www.bb.com", relativePath: "", need: "www.bb.com"},
 		{absolutePath: "", relativePath: "/a", need: "/a"},
+		{absolutePath: "", relativePath: "ws://www.cc.com//c", need: "ws://www.cc.com/c"},
+		{absolutePath: "wss://www.cc.com", relativePath: "/c", need: "wss://www.cc.com/c"},
 	}
 
 	for _, v := range test {
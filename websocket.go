@@ -0,0 +1,340 @@
+package gout
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// websocket消息类型, 和RFC 6455的opcode一致
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+)
+
+var (
+	// WebSocket()建立连接之后发送ping的间隔, 0表示不发送
+	DefaultWebSocketPingInterval = 30 * time.Second
+
+	// Close等待服务端回复关闭帧的时间
+	DefaultWebSocketCloseTimeout = time.Second
+)
+
+var ErrWebSocketMessageType = errors.New("gout:unexpected websocket message type")
+
+// 这些握手header由websocket库设置, 不能重复
+var webSocketHandshakeHeaders = map[string]bool{
+	"Upgrade":                  true,
+	"Connection":               true,
+	"Sec-Websocket-Key":        true,
+	"Sec-Websocket-Version":    true,
+	"Sec-Websocket-Extensions": true,
+}
+
+// WebSocket 使用SetHeader, SetQuery, SetCookies, 认证等设置发起websocket握手
+// http://, https://会换成ws://, wss://. 使用Client的Transport里的代理, TLS和UnixSocket设置
+// 握手失败(非101)返回*HTTPError
+func (df *DataFlow) WebSocket() (*WebSocketConn, error) {
+	defer df.Req.Reset()
+
+	if df.Req.err != nil {
+		return nil, df.Req.err
+	}
+
+	req, err := df.Req.request()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := df.out.webSocketDialer(df.Req.route, req)
+	if err != nil {
+		return nil, err
+	}
+
+	u := *req.URL
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+
+	header := make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		if !webSocketHandshakeHeaders[k] {
+			header[k] = v
+		}
+	}
+
+	conn, resp, err := d.DialContext(req.Context(), u.String(), header)
+	if err != nil {
+		if err == websocket.ErrBadHandshake && resp != nil {
+			return nil, webSocketHandshakeError(resp)
+		}
+		return nil, err
+	}
+
+	c := newWebSocketConn(conn, resp)
+	c.KeepAlive(DefaultWebSocketPingInterval)
+	return c, nil
+}
+
+// webSocketDialer 复用Client的Transport里的代理, TLS和拨号设置
+// 请求的SetProxy, UnixSocket优先, 然后是SetProxyFunc
+func (g *Gout) webSocketDialer(r *route, req *http.Request) (*websocket.Dialer, error) {
+	if g.transport.err != nil {
+		return nil, g.transport.err
+	}
+
+	var t *http.Transport
+	var err error
+
+	switch {
+	case r != nil:
+		var rt *routedTransport
+		if rt, err = g.routeTransport(g.Client, r); err == nil {
+			t = rt.h1
+		}
+	case g.transport.router != nil:
+		t, err = g.transport.router.transport(req)
+	default:
+		t, err = g.h1Transport(g.Client)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	d := &websocket.Dialer{
+		Proxy:           t.Proxy,
+		TLSClientConfig: t.TLSClientConfig,
+		NetDialContext:  t.DialContext,
+		Jar:             g.Client.Jar,
+	}
+
+	// UnixSocket设置的是Dial
+	if t.Dial != nil {
+		d.NetDial = t.Dial
+		d.NetDialContext = nil
+	}
+
+	return d, nil
+}
+
+func webSocketHandshakeError(resp *http.Response) error {
+	var body []byte
+	if resp.Body != nil {
+		body, _ = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}
+}
+
+// WebSocketConn websocket连接
+// 同一时间只能有一个goroutine读, 写方法可以被多个goroutine同时调用
+type WebSocketConn struct {
+	// 握手的响应, body已经关闭
+	Response *http.Response
+
+	conn *websocket.Conn
+	wmu  sync.Mutex
+	// 正在读的goroutine持有它, Close通过它判断需不需要自己读服务端的关闭帧
+	rsem chan struct{}
+
+	pingInterval int64 // time.Duration, 原子操作
+	pingMu       sync.Mutex
+	stopPing     chan struct{}
+
+	readDone  chan struct{}
+	readOnce  sync.Once
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newWebSocketConn(conn *websocket.Conn, resp *http.Response) *WebSocketConn {
+	c := &WebSocketConn{
+		Response: resp,
+		conn:     conn,
+		rsem:     make(chan struct{}, 1),
+		readDone: make(chan struct{}),
+	}
+
+	conn.SetPongHandler(func(string) error {
+		c.extendReadDeadline()
+		return nil
+	})
+
+	// 和默认的处理一样回复pong, 同时延长读超时
+	conn.SetPingHandler(func(data string) error {
+		c.extendReadDeadline()
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+
+	return c
+}
+
+// KeepAlive 每隔d发送一次ping, 2*d内没有收到任何数据时读操作返回超时错误
+// d<=0时停止发送. pong是在读消息的时候处理的, 需要有goroutine在读
+func (c *WebSocketConn) KeepAlive(d time.Duration) {
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+
+	if c.stopPing != nil {
+		close(c.stopPing)
+		c.stopPing = nil
+	}
+
+	atomic.StoreInt64(&c.pingInterval, int64(d))
+	c.extendReadDeadline()
+	if d <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	c.stopPing = stop
+	go func() {
+		tk := time.NewTicker(d)
+		defer tk.Stop()
+
+		for {
+			select {
+			case <-tk.C:
+				if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(d)); err != nil {
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func (c *WebSocketConn) extendReadDeadline() {
+	d := time.Duration(atomic.LoadInt64(&c.pingInterval))
+	if d <= 0 {
+		c.conn.SetReadDeadline(time.Time{})
+		return
+	}
+	c.conn.SetReadDeadline(time.Now().Add(2 * d))
+}
+
+// ReadMessage 读取一条文本或者二进制消息, 服务端的ping在这里自动回复
+// 服务端关闭连接时返回的错误可以用IsWebSocketClose判断
+func (c *WebSocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	c.rsem <- struct{}{}
+	defer func() { <-c.rsem }()
+
+	messageType, data, err = c.conn.ReadMessage()
+	if err != nil {
+		c.readOnce.Do(func() { close(c.readDone) })
+		return messageType, data, err
+	}
+
+	c.extendReadDeadline()
+	return messageType, data, nil
+}
+
+// ReadText 读取一条文本消息
+func (c *WebSocketConn) ReadText() (string, error) {
+	typ, data, err := c.ReadMessage()
+	if err != nil {
+		return "", err
+	}
+
+	if typ != TextMessage {
+		return "", ErrWebSocketMessageType
+	}
+	return string(data), nil
+}
+
+// ReadJSON 读取一条消息并解析到obj里, 使用RegisterCodec注册的json
+func (c *WebSocketConn) ReadJSON(obj interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	d := codecDecode{name: "json", obj: obj}
+	return d.Decode(bytes.NewReader(data))
+}
+
+// WriteMessage 发送一条TextMessage或者BinaryMessage
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.conn.WriteMessage(messageType, data)
+}
+
+func (c *WebSocketConn) WriteText(text string) error {
+	return c.WriteMessage(TextMessage, []byte(text))
+}
+
+func (c *WebSocketConn) WriteBinary(data []byte) error {
+	return c.WriteMessage(BinaryMessage, data)
+}
+
+// WriteJSON 把obj编码成json, 作为文本消息发送
+func (c *WebSocketConn) WriteJSON(obj interface{}) error {
+	var buf bytes.Buffer
+	e := codecEncode{name: "json", obj: obj}
+	if err := e.Encode(&buf); err != nil {
+		return err
+	}
+
+	return c.WriteMessage(TextMessage, bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// Close 发送关闭帧(1000), 等服务端回复关闭帧或者DefaultWebSocketCloseTimeout之后关闭连接
+// 有goroutine在ReadMessage时由它读到服务端的关闭帧, 否则Close自己读
+func (c *WebSocketConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.KeepAlive(0)
+
+		deadline := time.Now().Add(DefaultWebSocketCloseTimeout)
+		msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		if err := c.conn.WriteControl(websocket.CloseMessage, msg, deadline); err == nil {
+			c.waitClose(deadline)
+		}
+
+		c.closeErr = c.conn.Close()
+	})
+
+	return c.closeErr
+}
+
+func (c *WebSocketConn) waitClose(deadline time.Time) {
+	select {
+	case c.rsem <- struct{}{}:
+		// 没有人在读, 丢弃剩下的消息直到收到关闭帧
+		defer func() { <-c.rsem }()
+		c.conn.SetReadDeadline(deadline)
+		for {
+			if _, _, err := c.conn.NextReader(); err != nil {
+				return
+			}
+		}
+	case <-c.readDone:
+	case <-time.After(time.Until(deadline)):
+	}
+}
+
+// IsWebSocketClose 对端正常关闭(1000, 1001)时ReadMessage返回的错误
+func IsWebSocketClose(err error) bool {
+	return websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway)
+}
@@ -0,0 +1,206 @@
+package gout
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+type testWebSocketServer struct {
+	pings  int32
+	closed chan int
+}
+
+func (s *testWebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/forbidden" {
+		http.Error(w, "forbidden", 403)
+		return
+	}
+
+	var up websocket.Upgrader
+	conn, err := up.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	conn.SetPingHandler(func(data string) error {
+		atomic.AddInt32(&s.pings, 1)
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	// 第一条消息是握手时的header, query, cookie
+	cookie, _ := r.Cookie("c")
+	if cookie == nil {
+		cookie = &http.Cookie{}
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte(r.Header.Get("X-Token")+"|"+r.URL.Query().Get("q")+"|"+cookie.Value))
+
+	for {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			if e, ok := err.(*websocket.CloseError); ok && s.closed != nil {
+				s.closed <- e.Code
+			}
+			return
+		}
+
+		if err = conn.WriteMessage(typ, data); err != nil {
+			return
+		}
+	}
+}
+
+func Test_WebSocket_Message(t *testing.T) {
+	s := &testWebSocketServer{closed: make(chan int, 1)}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	conn, err := GET(ts.URL + "/echo").
+		SetHeader(H{"X-Token": "token"}).
+		SetQuery(H{"q": "query"}).
+		SetCookies(&http.Cookie{Name: "c", Value: "cookie"}).
+		WebSocket()
+	assert.NoError(t, err)
+	assert.Equal(t, 101, conn.Response.StatusCode)
+
+	text, err := conn.ReadText()
+	assert.NoError(t, err)
+	assert.Equal(t, "token|query|cookie", text)
+
+	assert.NoError(t, conn.WriteText("hello"))
+	text, err = conn.ReadText()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", text)
+
+	assert.NoError(t, conn.WriteBinary([]byte{0, 1, 2}))
+	typ, data, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, BinaryMessage, typ)
+	assert.Equal(t, []byte{0, 1, 2}, data)
+
+	assert.NoError(t, conn.WriteBinary([]byte("binary")))
+	_, err = conn.ReadText()
+	assert.Equal(t, ErrWebSocketMessageType, err)
+
+	type msg struct {
+		N int `json:"n"`
+	}
+	assert.NoError(t, conn.WriteJSON(msg{N: 1}))
+	var m msg
+	assert.NoError(t, conn.ReadJSON(&m))
+	assert.Equal(t, 1, m.N)
+
+	// 没有goroutine在读的时候Close自己等服务端的关闭帧
+	assert.NoError(t, conn.Close())
+	assert.Equal(t, websocket.CloseNormalClosure, <-s.closed)
+}
+
+func Test_WebSocket_KeepAlive(t *testing.T) {
+	s := &testWebSocketServer{closed: make(chan int, 1)}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	conn, err := GET(ts.URL).WebSocket()
+	assert.NoError(t, err)
+	// 读超时是2倍的间隔, 间隔太小在繁忙的机器上会读超时
+	conn.KeepAlive(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&s.pings) >= 3)
+
+	// 读的goroutine收到服务端回复的关闭帧
+	assert.NoError(t, conn.Close())
+	assert.Equal(t, websocket.CloseNormalClosure, <-s.closed)
+	assert.True(t, IsWebSocketClose(<-done))
+}
+
+func Test_WebSocket_Handshake(t *testing.T) {
+	ts := httptest.NewServer(&testWebSocketServer{})
+	defer ts.Close()
+
+	_, err := GET(ts.URL + "/forbidden").WebSocket()
+	e, ok := err.(*HTTPError)
+	assert.True(t, ok)
+	assert.Equal(t, 403, e.StatusCode)
+	assert.Contains(t, string(e.Body), "forbidden")
+
+	_, err = New(&http.Client{Transport: &TransportFail{}}).GET(ts.URL).WebSocket()
+	assert.Error(t, err)
+}
+
+// ws://, wss://是绝对地址, 不拼接SetBaseURL; SetBaseURL也可以是ws://
+func Test_WebSocket_BaseURL(t *testing.T) {
+	ts := httptest.NewServer(&testWebSocketServer{})
+	defer ts.Close()
+
+	wsURL := "ws://" + strings.TrimPrefix(ts.URL, "http://")
+	for _, g := range []*Gout{
+		New().SetBaseURL("http://127.0.0.1:1/api"),
+		New().SetBaseURL(wsURL),
+	} {
+		df := g.GET(wsURL + "/echo")
+		assert.Equal(t, wsURL+"/echo", df.Req.url)
+
+		conn, err := df.SetHeader(H{"X-Token": "base"}).WebSocket()
+		assert.NoError(t, err)
+		text, err := conn.ReadText()
+		assert.NoError(t, err)
+		assert.Equal(t, "base||", text)
+		assert.NoError(t, conn.Close())
+	}
+
+	df := New().SetBaseURL(wsURL).GET("/echo")
+	assert.Equal(t, wsURL+"/echo", df.Req.url)
+	conn, err := df.WebSocket()
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+}
+
+// 复用Client的TLS和UnixSocket设置
+func Test_WebSocket_Transport(t *testing.T) {
+	ts := httptest.NewTLSServer(&testWebSocketServer{})
+	defer ts.Close()
+
+	conn, err := New(ts.Client()).GET(ts.URL).SetHeader(H{"X-Token": "tls"}).WebSocket()
+	assert.NoError(t, err)
+	text, err := conn.ReadText()
+	assert.NoError(t, err)
+	assert.Equal(t, "tls||", text)
+	assert.NoError(t, conn.Close())
+
+	path := "./ws.sock"
+	defer os.Remove(path)
+
+	l, err := net.Listen("unix", path)
+	assert.NoError(t, err)
+	srv := &http.Server{Handler: &testWebSocketServer{}}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	c := http.Client{}
+	conn, err = New(&c).UnixSocket(path).GET("ws://unix/").SetHeader(H{"X-Token": "unix"}).WebSocket()
+	assert.NoError(t, err)
+	text, err = conn.ReadText()
+	assert.NoError(t, err)
+	assert.Equal(t, "unix||", text)
+	assert.NoError(t, conn.Close())
+}